@@ -0,0 +1,226 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"bufio"
+	"cloud.google.com/go/logging"
+	"errors"
+	"fmt"
+	"math/rand"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// AccessLogOption configures the handler returned by AccessLogHandler().
+type AccessLogOption func(*accessLogConfig)
+
+type accessLogConfig struct {
+	successSampleRate float64
+	trustedHops       int
+}
+
+// AccessLogSuccessSampleRate sets the fraction of requests with a 2xx status
+// code that are logged. A rate of 1 (the default) logs every request and a rate
+// of 0 logs none of them. Requests with any other status code are always logged.
+func AccessLogSuccessSampleRate(rate float64) AccessLogOption {
+	return func(c *accessLogConfig) {
+		c.successSampleRate = rate
+	}
+}
+
+// AccessLogTrustedHops sets the number of trailing X-Forwarded-For entries that are
+// appended by trusted proxies. The default is DefaultTrustedHops. See RemoteIPTrustedHops().
+func AccessLogTrustedHops(hops int) AccessLogOption {
+	return func(c *accessLogConfig) {
+		c.trustedHops = hops
+	}
+}
+
+// AccessLogHandler returns a handler that calls h and then logs the request to
+// lg as a logging.Entry with HTTPRequest set. The severity is Error for 5xx status
+// codes, Warning for 4xx status codes and Info otherwise. If the request context
+// has a TraceContext (see TraceHandler()), then the entry will be part of the trace.
+//
+//	srv, err := gke.NewServer(ctx, gke.AccessLogHandler(lg, mux), lg)
+func AccessLogHandler(lg Logger, h http.Handler, opts ...AccessLogOption) http.Handler {
+	cfg := accessLogConfig{successSampleRate: 1, trustedHops: DefaultTrustedHops}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w}
+		h.ServeHTTP(rec, r)
+
+		status := rec.Status()
+		if status >= 200 && status < 300 && !sampled(cfg.successSampleRate) {
+			return
+		}
+
+		entry := logging.Entry{
+			Severity: statusSeverity(status),
+			Payload:  fmt.Sprintf("%s %s %d", r.Method, r.URL.RequestURI(), status),
+			HTTPRequest: &logging.HTTPRequest{
+				Request:      r,
+				RequestSize:  requestSize(r),
+				Status:       status,
+				ResponseSize: rec.size,
+				Latency:      time.Since(start),
+				LocalIP:      localIP(r),
+				RemoteIP:     RemoteIPTrustedHops(r, cfg.trustedHops),
+			},
+		}
+		lg.WithContext(r.Context()).Log(entry)
+	})
+}
+
+func sampled(rate float64) bool {
+	switch {
+	case rate >= 1:
+		return true
+	case rate <= 0:
+		return false
+	default:
+		return rand.Float64() < rate
+	}
+}
+
+func statusSeverity(status int) logging.Severity {
+	switch {
+	case status >= 500:
+		return logging.Error
+	case status >= 400:
+		return logging.Warning
+	default:
+		return logging.Info
+	}
+}
+
+func requestSize(r *http.Request) int64 {
+	if r.ContentLength > 0 {
+		return r.ContentLength
+	}
+	return 0
+}
+
+// DefaultTrustedHops is the number of trailing X-Forwarded-For entries appended by
+// the Google Cloud load balancers in front of GKE ingresses, which append
+// "<client-ip>, <load-balancer-ip>" to the header.
+const DefaultTrustedHops = 2
+
+// RemoteIP returns the IP address of the client that issued the request, assuming
+// that it was received through a Google Cloud load balancer. It is equivalent to
+// RemoteIPTrustedHops(r, DefaultTrustedHops).
+func RemoteIP(r *http.Request) string {
+	return RemoteIPTrustedHops(r, DefaultTrustedHops)
+}
+
+// RemoteIPTrustedHops returns the IP address of the client that issued the request.
+// hops is the number of trailing X-Forwarded-For entries that are appended by trusted
+// proxies, the first of which is the client address. Entries before them are supplied
+// by the client and are ignored. If hops is 0, or the header has fewer than hops entries,
+// or the entry is not an IP address, then the address from r.RemoteAddr is returned.
+func RemoteIPTrustedHops(r *http.Request, hops int) string {
+	if xff := r.Header.Values("X-Forwarded-For"); hops > 0 && len(xff) > 0 {
+		entries := strings.Split(strings.Join(xff, ","), ",")
+		if len(entries) >= hops {
+			ip := strings.TrimSpace(entries[len(entries)-hops])
+			if net.ParseIP(ip) != nil {
+				return ip
+			}
+		}
+	}
+	return hostOnly(r.RemoteAddr)
+}
+
+func localIP(r *http.Request) string {
+	if addr, ok := r.Context().Value(http.LocalAddrContextKey).(net.Addr); ok {
+		return hostOnly(addr.String())
+	}
+	return ""
+}
+
+func hostOnly(addr string) string {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return addr
+	}
+	return host
+}
+
+// statusRecorder records the status code and response size written to a http.ResponseWriter.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int64
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	if s.status == 0 {
+		s.status = status
+	}
+	s.ResponseWriter.WriteHeader(status)
+}
+
+func (s *statusRecorder) Write(b []byte) (int, error) {
+	if s.status == 0 {
+		s.status = http.StatusOK
+	}
+	n, err := s.ResponseWriter.Write(b)
+	s.size += int64(n)
+	return n, err
+}
+
+// Status returns the recorded status code. If nothing was written, then
+// http.StatusOK is returned because that is what the server will send. If the
+// connection was hijacked first, such as for a WebSocket upgrade, then the
+// server sends nothing, and http.StatusSwitchingProtocols is recorded.
+func (s *statusRecorder) Status() int {
+	if s.status == 0 {
+		return http.StatusOK
+	}
+	return s.status
+}
+
+// Flush implements http.Flusher.
+func (s *statusRecorder) Flush() {
+	if f, ok := s.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// Hijack implements http.Hijacker.
+func (s *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	if h, ok := s.ResponseWriter.(http.Hijacker); ok {
+		conn, rw, err := h.Hijack()
+		if err == nil && s.status == 0 {
+			s.status = http.StatusSwitchingProtocols
+		}
+		return conn, rw, err
+	}
+	return nil, nil, errors.New("gke: underlying http.ResponseWriter does not implement http.Hijacker")
+}
+
+// Unwrap returns the underlying http.ResponseWriter.
+func (s *statusRecorder) Unwrap() http.ResponseWriter {
+	return s.ResponseWriter
+}
@@ -0,0 +1,163 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func TestAccessLogHandler_severity(t *testing.T) {
+	for _, tc := range []struct {
+		status   int
+		severity logging.Severity
+	}{
+		{http.StatusOK, logging.Info},
+		{http.StatusFound, logging.Info},
+		{http.StatusNotFound, logging.Warning},
+		{http.StatusServiceUnavailable, logging.Error},
+	} {
+		rec := gketest.NewRecorder()
+		h := gke.AccessLogHandler(rec.Logger("access"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(tc.status)
+		}))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+		es := rec.Entries()
+		if len(es) != 1 || es[0].Severity != tc.severity {
+			t.Errorf("expected 1 entry with severity %v for status %d, got %v", tc.severity, tc.status, es)
+		}
+	}
+}
+
+func TestAccessLogHandler_sampling(t *testing.T) {
+	rec := gketest.NewRecorder()
+	status := http.StatusOK
+	h := gke.AccessLogHandler(rec.Logger("access"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+	}), gke.AccessLogSuccessSampleRate(0))
+
+	for i := 0; i < 10; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}
+	status = http.StatusBadRequest
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	es := rec.Entries()
+	if len(es) != 1 || es[0].HTTPRequest.Status != http.StatusBadRequest {
+		t.Errorf("expected only the 400 response to be logged, got %v", es)
+	}
+}
+
+func TestAccessLogHandler_httpRequest(t *testing.T) {
+	rec := gketest.NewRecorder()
+	h := gke.AccessLogHandler(rec.Logger("access"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("hello"))
+	}))
+
+	r := httptest.NewRequest("POST", "/items?id=1", strings.NewReader("body"))
+	r.RemoteAddr = "10.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "203.0.113.7, 130.211.0.1")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	es := rec.Entries()
+	if len(es) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(es))
+	}
+	hr := es[0].HTTPRequest
+	if hr.Request != r || hr.Status != http.StatusOK || hr.RequestSize != 4 || hr.ResponseSize != 5 || hr.RemoteIP != "203.0.113.7" || hr.Latency <= 0 {
+		t.Errorf("unexpected HTTPRequest %+v", hr)
+	}
+	if want := "POST /items?id=1 200"; es[0].Payload != want {
+		t.Errorf("expected payload %q, got %q", want, es[0].Payload)
+	}
+}
+
+func TestAccessLogHandler_hijack(t *testing.T) {
+	rec := gketest.NewRecorder()
+	srv := httptest.NewServer(gke.AccessLogHandler(rec.Logger("access"), http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		_ = rw.Flush()
+	})))
+	defer srv.Close()
+
+	r, err := http.NewRequest("GET", srv.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Header.Set("Connection", "Upgrade")
+	r.Header.Set("Upgrade", "websocket")
+	resp, err := http.DefaultClient.Do(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	e, err := rec.WaitFor(ctx, gketest.LogID("access"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if e.HTTPRequest.Status != http.StatusSwitchingProtocols {
+		t.Errorf("expected status %d for a hijacked connection, got %d", http.StatusSwitchingProtocols, e.HTTPRequest.Status)
+	}
+}
+
+func TestRemoteIPTrustedHops(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		xff  []string
+		hops int
+		want string
+	}{
+		{"no header", nil, 2, "10.0.0.1"},
+		{"load balancer", []string{"203.0.113.7, 130.211.0.1"}, 2, "203.0.113.7"},
+		{"spoofed", []string{"1.2.3.4, 203.0.113.7, 130.211.0.1"}, 2, "203.0.113.7"},
+		{"multiple headers", []string{"1.2.3.4", "203.0.113.7, 130.211.0.1"}, 2, "203.0.113.7"},
+		{"too few entries", []string{"203.0.113.7"}, 2, "10.0.0.1"},
+		{"one hop", []string{"1.2.3.4, 203.0.113.7"}, 1, "203.0.113.7"},
+		{"untrusted", []string{"1.2.3.4"}, 0, "10.0.0.1"},
+		{"not an ip", []string{"unknown, 130.211.0.1"}, 2, "10.0.0.1"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			r.RemoteAddr = "10.0.0.1:1234"
+			for _, v := range tc.xff {
+				r.Header.Add("X-Forwarded-For", v)
+			}
+			if got := gke.RemoteIPTrustedHops(r, tc.hops); got != tc.want {
+				t.Errorf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
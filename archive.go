@@ -0,0 +1,48 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"context"
+
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// ArchiveConfig configures a client returned by NewArchiveLogClient().
+type ArchiveConfig = log.ArchiveConfig
+
+// ErrArchiveClosed is returned by LogSync() when an entry is logged to a client
+// returned by NewArchiveLogClient() after it has been closed. Entries logged with
+// Log() after Close are dropped and reported to os.Stderr.
+var ErrArchiveClosed = log.ErrArchiveClosed
+
+// NewArchiveLogClient returns a log client that archives entries in bucket as
+// newline-delimited JSON objects, partitioned by log ID and date. It is useful
+// for keeping logs longer than the Cloud Logging retention period, and can be
+// combined with the default client using LogClientSink(). Buffered entries are
+// written when the client is closed.
+//
+// The context is used for writing objects and should remain open for the life of the client.
+//
+//	sc, cleanup, err := gke.NewStorageClient(ctx)
+//	...
+//	archive := gke.NewArchiveLogClient(context.Background(), sc, "my-audit-logs", gke.ArchiveConfig{Gzip: true})
+//	lc, cleanup, err := gke.NewLogClient(ctx, gke.LogClientSink(archive, logging.Default))
+func NewArchiveLogClient(ctx context.Context, sc StorageClient, bucket string, cfg ArchiveConfig) LogClient {
+	return newLogClient(log.NewArchiveClient(ctx, sc.Bucket(bucket), cfg))
+}
@@ -0,0 +1,183 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"bytes"
+	"cloud.google.com/go/logging"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func ExampleNewArchiveLogClient() {
+	fake := gketest.NewFakeStorageServer()
+	defer fake.Close()
+
+	// Examples cannot use testing.T.Setenv(), so restore the previous value.
+	if prev, ok := os.LookupEnv("STORAGE_EMULATOR_HOST"); ok {
+		defer func() { _ = os.Setenv("STORAGE_EMULATOR_HOST", prev) }()
+	} else {
+		defer func() { _ = os.Unsetenv("STORAGE_EMULATOR_HOST") }()
+	}
+	_ = os.Setenv("STORAGE_EMULATOR_HOST", fake.Host)
+
+	ctx := context.Background()
+	sc, cleanup, err := gke.NewStorageClient(ctx)
+	if err != nil {
+		panic(err)
+	}
+	defer cleanup()
+
+	lc := gke.NewArchiveLogClient(ctx, sc, "audit-bucket", gke.ArchiveConfig{Prefix: "archive", Gzip: true})
+	lg := lc.Logger("audit")
+	lg.Notice("user created")
+	lg.Infow("user updated", "user", "alice")
+
+	if err := lc.Close(); err != nil {
+		panic(err)
+	}
+
+	for _, o := range fake.Objects() {
+		fmt.Println(o.Bucket, strings.HasPrefix(o.Name, "archive/audit/"), strings.HasSuffix(o.Name, ".ndjson.gz"), o.ContentEncoding)
+
+		zr, err := gzip.NewReader(bytes.NewReader(o.Content))
+		if err != nil {
+			panic(err)
+		}
+		b, err := ioutil.ReadAll(zr)
+		if err != nil {
+			panic(err)
+		}
+
+		for _, line := range strings.Split(strings.TrimSpace(string(b)), "\n") {
+			var rec struct {
+				LogName     string
+				Severity    string
+				TextPayload string
+				JSONPayload map[string]interface{}
+			}
+			if err := json.Unmarshal([]byte(line), &rec); err != nil {
+				panic(err)
+			}
+			fmt.Println(rec.LogName, rec.Severity, rec.TextPayload, rec.JSONPayload)
+		}
+	}
+
+	// Output:
+	// audit-bucket true true gzip
+	// audit Notice user created map[]
+	// audit Info  map[message:user updated user:alice]
+}
+
+func newTestArchiveLogClient(t *testing.T, cfg gke.ArchiveConfig) (gke.LogClient, *gketest.FakeStorageServer) {
+	fake := gketest.NewFakeStorageServer()
+	t.Cleanup(fake.Close)
+	t.Setenv("STORAGE_EMULATOR_HOST", fake.Host)
+
+	sc, cleanup, err := gke.NewStorageClient(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(cleanup)
+
+	return gke.NewArchiveLogClient(context.Background(), sc, "audit-bucket", cfg), fake
+}
+
+func TestNewArchiveLogClient_closed(t *testing.T) {
+	lc, fake := newTestArchiveLogClient(t, gke.ArchiveConfig{MaxObjectBytes: 1})
+	lg := lc.Logger("audit")
+	if err := lc.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := context.Background()
+	lg.Notice("after close")
+	err := lg.LogSync(ctx, logging.Entry{Severity: logging.Notice, Payload: "after close"})
+	if !errors.Is(err, gke.ErrArchiveClosed) {
+		t.Errorf("expected ErrArchiveClosed, got %v", err)
+	}
+	if n := len(fake.Objects()); n != 0 {
+		t.Errorf("expected no objects to be written after Close, got %d", n)
+	}
+}
+
+func TestNewArchiveLogClient_rollover(t *testing.T) {
+	t.Run("size", func(t *testing.T) {
+		lc, fake := newTestArchiveLogClient(t, gke.ArchiveConfig{MaxObjectBytes: 1})
+		defer lc.Close()
+		lg := lc.Logger("audit")
+		for i := 1; i <= 3; i++ {
+			lg.Noticef("entry %d", i)
+			if !waitForObjects(fake, i) {
+				t.Fatalf("expected an object to be written for each full batch, got %d after %d entries", len(fake.Objects()), i)
+			}
+		}
+	})
+
+	t.Run("age", func(t *testing.T) {
+		lc, fake := newTestArchiveLogClient(t, gke.ArchiveConfig{MaxObjectAge: 40 * time.Millisecond})
+		defer lc.Close()
+		lc.Logger("audit").Notice("aged")
+		if !waitForObjects(fake, 1) {
+			t.Fatal("expected the object to be written once it is older than MaxObjectAge")
+		}
+	})
+
+	t.Run("date", func(t *testing.T) {
+		lc, fake := newTestArchiveLogClient(t, gke.ArchiveConfig{})
+		lg := lc.Logger("audit")
+		day := time.Date(2020, 1, 2, 23, 59, 59, 0, time.UTC)
+		lg.Log(logging.Entry{Timestamp: day, Payload: "before midnight"})
+		lg.Log(logging.Entry{Timestamp: day.Add(time.Second), Payload: "after midnight"})
+		if err := lc.Close(); err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, o := range fake.Objects() {
+			names = append(names, o.Name)
+		}
+		sort.Strings(names)
+		if len(names) != 2 || !strings.HasPrefix(names[0], "audit/2020/01/02/") || !strings.HasPrefix(names[1], "audit/2020/01/03/") {
+			t.Errorf("expected an object per date, got %q", names)
+		}
+	})
+}
+
+func waitForObjects(fake *gketest.FakeStorageServer, n int) bool {
+	deadline := time.Now().Add(5 * time.Second)
+	for len(fake.Objects()) < n {
+		if time.Now().After(deadline) {
+			return false
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	return true
+}
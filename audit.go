@@ -0,0 +1,159 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// AuditLogID is the ID of the log that audit events are written to. See LogClient.AuditLogger().
+const AuditLogID = "audit"
+
+// AuditOutcome is the result of an audited action.
+type AuditOutcome string
+
+const (
+	// AuditSuccess means the action was performed.
+	AuditSuccess AuditOutcome = "success"
+	// AuditFailure means the action was attempted, but failed.
+	AuditFailure AuditOutcome = "failure"
+	// AuditDenied means the principal was not permitted to perform the action.
+	AuditDenied AuditOutcome = "denied"
+)
+
+// AuditEvent records that a principal performed an action on a resource. It is the
+// payload of entries written by AuditLogger, and its JSON field names are stable.
+type AuditEvent struct {
+	// Principal identifies who performed the action, such as a user or service account. It is required.
+	Principal string `json:"principal"`
+	// Action is what was done, such as "projects.delete". It is required.
+	Action string `json:"action"`
+	// Resource identifies what the action was done to, such as "projects/my-project". It is required.
+	Resource string `json:"resource"`
+	// Outcome is the result of the action. It is required.
+	Outcome AuditOutcome `json:"outcome"`
+	// RequestID identifies the request that caused the action. If it is empty, then
+	// it is taken from the context passed to AuditLogger.Log() (see WithRequestID()).
+	RequestID string `json:"requestId,omitempty"`
+	// Metadata holds additional details about the action. It must be serializable to JSON.
+	Metadata map[string]interface{} `json:"metadata,omitempty"`
+}
+
+// ErrInvalidAuditEvent is returned by AuditEvent.Validate() and AuditLogger.Log()
+// when an AuditEvent does not conform to the audit schema.
+var ErrInvalidAuditEvent = errors.New("invalid audit event")
+
+// Validate returns an error wrapping ErrInvalidAuditEvent if a required field is
+// missing, the outcome is unknown or the metadata cannot be serialized to JSON.
+func (e AuditEvent) Validate() error {
+	var missing []string
+	if e.Principal == "" {
+		missing = append(missing, "principal")
+	}
+	if e.Action == "" {
+		missing = append(missing, "action")
+	}
+	if e.Resource == "" {
+		missing = append(missing, "resource")
+	}
+	if e.Outcome == "" {
+		missing = append(missing, "outcome")
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("%w: missing %s", ErrInvalidAuditEvent, strings.Join(missing, ", "))
+	}
+
+	switch e.Outcome {
+	case AuditSuccess, AuditFailure, AuditDenied:
+	default:
+		return fmt.Errorf("%w: unknown outcome %q", ErrInvalidAuditEvent, e.Outcome)
+	}
+
+	if _, err := json.Marshal(e.Metadata); err != nil {
+		return fmt.Errorf("%w: metadata: %v", ErrInvalidAuditEvent, err)
+	}
+
+	return nil
+}
+
+// AuditLogger writes AuditEvents to a dedicated log. See LogClient.AuditLogger().
+type AuditLogger struct {
+	lg Logger
+}
+
+// AuditLogger returns an AuditLogger that writes to the log with ID AuditLogID.
+// Audit events are never dropped by the client's minimum severity (see
+// LogClientMinSeverity()) or sampling (see LogClientSampling()). Like the loggers
+// returned by LogClient.Logger(), the audit logger is shared by all calls and is
+// flushed by FlushLoggers().
+//
+//	al := lc.AuditLogger()
+//	err := al.Log(ctx, gke.AuditEvent{
+//		Principal: "alice@example.com",
+//		Action:    "projects.delete",
+//		Resource:  "projects/my-project",
+//		Outcome:   gke.AuditSuccess,
+//	})
+func (lc LogClient) AuditLogger() AuditLogger {
+	if lc.audit == nil {
+		return AuditLogger{lc.Logger(AuditLogID)}
+	}
+	newLogger := func() log.Logger { return lc.audit.Logger(AuditLogID) }
+	if lc.reg == nil {
+		return AuditLogger{Logger{Logger: newLogger()}}
+	}
+	return AuditLogger{Logger{Logger: lc.reg.auditLogger(newLogger)}}
+}
+
+// Log validates event (see AuditEvent.Validate()) and writes it synchronously with
+// LogSync(), so that it is not lost if the process exits. Successful events are logged
+// with Notice severity, and other events with Warning severity. The entry is stamped
+// with the trace context and request ID stored in ctx (see Logger.WithContext()).
+// Invalid events are not written.
+func (a AuditLogger) Log(ctx context.Context, event AuditEvent) error {
+	if event.RequestID == "" {
+		event.RequestID, _ = RequestIDFromContext(ctx)
+	}
+
+	err := event.Validate()
+	if err != nil {
+		return err
+	}
+
+	severity := logging.Notice
+	if event.Outcome != AuditSuccess {
+		severity = logging.Warning
+	}
+
+	entry := logging.Entry{Timestamp: time.Now(), Severity: severity, Payload: event}
+	SetupSourceLocation(&entry, 1)
+
+	err = a.lg.WithContext(ctx).LogSync(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("failed to write audit event: %w", err)
+	}
+	return nil
+}
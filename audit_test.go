@@ -0,0 +1,123 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"testing"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func ExampleAuditLogger_Log() {
+	rec := gketest.NewRecorder()
+	al := rec.LogClient().AuditLogger()
+
+	ctx := gke.WithRequestID(context.Background(), "req-1")
+	err := al.Log(ctx, gke.AuditEvent{
+		Principal: "alice@example.com",
+		Action:    "projects.delete",
+		Resource:  "projects/my-project",
+		Outcome:   gke.AuditDenied,
+		Metadata:  map[string]interface{}{"reason": "missing role"},
+	})
+	if err != nil {
+		panic(err)
+	}
+
+	err = al.Log(ctx, gke.AuditEvent{Principal: "alice@example.com", Outcome: gke.AuditSuccess})
+	fmt.Println(errors.Is(err, gke.ErrInvalidAuditEvent), err)
+
+	for _, e := range rec.Entries() {
+		fmt.Println(e.LogID, e.Severity, e.Labels[gke.RequestIDLabel], e.PayloadString())
+	}
+
+	// Output:
+	// true invalid audit event: missing action, resource
+	// audit Warning req-1 {"principal":"alice@example.com","action":"projects.delete","resource":"projects/my-project","outcome":"denied","requestId":"req-1","metadata":{"reason":"missing role"}}
+}
+
+func TestAuditLogger_minSeverity(t *testing.T) {
+	fake, err := gketest.NewFakeLoggingServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fake.Close()
+
+	md := &gke.MetadataType{ProjectID: "my-project"}
+	lc, cleanup, err := gke.NewGkeLogClient(context.Background(), md,
+		gke.LogClientAPIOptions(fake.ClientOptions()...),
+		gke.LogClientMinSeverity(logging.Error),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	ctx := context.Background()
+	err = lc.AuditLogger().Log(ctx, gke.AuditEvent{
+		Principal: "alice@example.com",
+		Action:    "projects.create",
+		Resource:  "projects/my-project",
+		Outcome:   gke.AuditSuccess,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n := len(fake.Entries(gke.AuditLogID)); n != 1 {
+		t.Errorf("expected the audit event to bypass the minimum severity, got %d entries", n)
+	}
+
+	err = lc.Logger("app").LogSync(ctx, logging.Entry{Severity: logging.Info, Payload: "filtered"})
+	if !errors.Is(err, gke.ErrFiltered) {
+		t.Errorf("expected LogSync of a filtered entry to return ErrFiltered, got %v", err)
+	}
+}
+
+func TestLogClient_AuditLogger_shared(t *testing.T) {
+	fake, err := gketest.NewFakeLoggingServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fake.Close()
+
+	md := &gke.MetadataType{ProjectID: "my-project"}
+	lc, cleanup, err := gke.NewGkeLogClient(context.Background(), md,
+		gke.LogClientAPIOptions(fake.ClientOptions()...),
+		gke.LogClientMinSeverity(logging.Error),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	// Each Cloud Logging logger runs a goroutine until the client is closed.
+	lc.AuditLogger()
+	before := runtime.NumGoroutine()
+	for i := 0; i < 100; i++ {
+		lc.AuditLogger()
+	}
+	if n := runtime.NumGoroutine() - before; n >= 10 {
+		t.Errorf("expected AuditLogger() to share one logger, got %d new goroutines after 100 calls", n)
+	}
+}
@@ -0,0 +1,47 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	logpb "google.golang.org/genproto/googleapis/logging/v2"
+	"os"
+	"time"
+
+	"github.com/ajjensen13/gke"
+)
+
+func ExampleNewConsoleLogClient() {
+	lg := gke.NewConsoleLogClient(os.Stdout).Logger("example")
+
+	_ = lg.LogSync(context.Background(), logging.Entry{
+		Timestamp:      time.Date(2020, 11, 16, 8, 30, 0, 0, time.UTC),
+		Severity:       logging.Warning,
+		Payload:        gke.NewKeyValues("slow request", "path", "/"),
+		Labels:         map[string]string{"env": "dev"},
+		SourceLocation: &logpb.LogEntrySourceLocation{File: "/go/pkg/mod/example.com/app@v1.0.0/main.go", Line: 42},
+	})
+
+	// Output:
+	// 08:30:00.000 WARNING   example example.com/app@v1.0.0/main.go:42 slow request
+	//     {
+	//       "path": "/"
+	//     }
+	//     labels: env="dev"
+}
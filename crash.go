@@ -0,0 +1,251 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"fmt"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// FlushTimeout bounds the time spent flushing loggers before the process
+// dies. See LogPanics(), Fatal() and Exit().
+var FlushTimeout = time.Second * 5
+
+var (
+	registriesMu sync.Mutex // protects below
+	registries   = map[*loggerRegistry]struct{}{}
+)
+
+// loggerRegistry records the loggers of a LogClient so that they are flushed
+// by FlushLoggers(). It is added to registries when the client is created and
+// removed when the client is closed.
+type loggerRegistry struct {
+	mu      sync.Mutex            // protects below
+	loggers map[string]log.Logger // by log ID
+	audit   log.Logger            // see LogClient.AuditLogger(); nil until it is provisioned
+
+	// pending holds the WithDedup() and WithSampling() wrappers that have
+	// pending reports.
+	pending *log.LoggerSet
+}
+
+func newLoggerRegistry() *loggerRegistry {
+	r := &loggerRegistry{loggers: make(map[string]log.Logger), pending: log.NewLoggerSet()}
+	registriesMu.Lock()
+	defer registriesMu.Unlock()
+	registries[r] = struct{}{}
+	return r
+}
+
+// logger returns the logger for logID, creating it with newLogger if needed.
+func (r *loggerRegistry) logger(logID string, newLogger func() log.Logger) log.Logger {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.loggers[logID]
+	if !ok {
+		l = newLogger()
+		r.loggers[logID] = l
+	}
+	return l
+}
+
+// auditLogger returns the audit logger, creating it with newLogger if needed.
+// It is kept apart from the loggers returned by logger() because it may be
+// provisioned from a different client for the same log ID.
+func (r *loggerRegistry) auditLogger(newLogger func() log.Logger) log.Logger {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.audit == nil {
+		r.audit = newLogger()
+	}
+	return r.audit
+}
+
+// unregister removes r from registries so that it is no longer flushed by
+// FlushLoggers(). It is a no-op if r is nil.
+func (r *loggerRegistry) unregister() {
+	if r == nil {
+		return
+	}
+	registriesMu.Lock()
+	defer registriesMu.Unlock()
+	delete(registries, r)
+}
+
+// flush flushes the wrappers with pending reports, then the loggers.
+func (r *loggerRegistry) flush() {
+	flushAll(r.pending.List())
+
+	r.mu.Lock()
+	ls := make([]log.Logger, 0, len(r.loggers)+1)
+	for _, l := range r.loggers {
+		ls = append(ls, l)
+	}
+	if r.audit != nil {
+		ls = append(ls, r.audit)
+	}
+	r.mu.Unlock()
+	flushAll(ls)
+}
+
+func flushAll(ls []log.Logger) {
+	var wg sync.WaitGroup
+	for _, l := range ls {
+		wg.Add(1)
+		go func(l log.Logger) {
+			defer wg.Done()
+			_ = l.Flush()
+		}(l)
+	}
+	wg.Wait()
+}
+
+// FlushLoggers flushes the loggers returned from LogClient.Logger() by clients
+// that have not been closed, along with the pending reports of loggers returned
+// from Logger.WithDedup() and Logger.WithSampling(). It returns once all of them
+// have been flushed or the timeout expires. FlushLoggers is called automatically
+// by LogPanics(), Fatal(), Exit() and AfterAliveContext().
+func FlushLoggers(timeout time.Duration) {
+	registriesMu.Lock()
+	rs := make([]*loggerRegistry, 0, len(registries))
+	for r := range registries {
+		rs = append(rs, r)
+	}
+	registriesMu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		var wg sync.WaitGroup
+		for _, r := range rs {
+			wg.Add(1)
+			go func(r *loggerRegistry) {
+				defer wg.Done()
+				r.flush()
+			}(r)
+		}
+		wg.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(timeout):
+	}
+}
+
+// LogPanics logs a recovered panic value and stack trace at Emergency severity,
+// flushes the loggers and then re-panics. It must be called directly by defer.
+// The entry is a ReportedErrorEvent so that the panic is picked up by Cloud Error
+// Reporting.
+//
+//	func main() {
+//		lg, cleanup, err := gke.NewLogger(context.Background())
+//		...
+//		defer gke.LogPanics(lg)
+//		...
+//	}
+func LogPanics(lg Logger) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	f := panicFrame()
+	ev := ReportedErrorEvent{
+		Type:           ReportedErrorEventType,
+		Message:        fmt.Sprintf("panic: %v\n\n%s", r, debug.Stack()),
+		ServiceContext: DefaultServiceContext(),
+		Context:        ErrorContext{ReportLocation: ReportLocation{FilePath: f.File, LineNumber: f.Line, FunctionName: f.Function}},
+	}
+	if err, ok := r.(error); ok {
+		ev.Errors = errorChain(err)
+	}
+
+	entry := logging.Entry{Severity: logging.Emergency, Payload: ev}
+	if f.PC != 0 {
+		log.SetupSourceLocationFrame(&entry, f)
+	}
+	logCrash(lg, entry)
+
+	panic(r)
+}
+
+// panicFrame returns the frame of the function that panicked when called
+// from a deferred function during a panic.
+func panicFrame() runtime.Frame {
+	var pcs [64]uintptr
+	n := runtime.Callers(1, pcs[:])
+	fs := runtime.CallersFrames(pcs[:n])
+
+	panicking := false
+	for {
+		f, more := fs.Next()
+		switch {
+		case f.Function == "runtime.gopanic":
+			panicking = true
+		case panicking && !strings.HasPrefix(f.Function, "runtime."):
+			return f
+		}
+		if !more {
+			return runtime.Frame{}
+		}
+	}
+}
+
+// Fatal logs err at Emergency severity, flushes the loggers and then exits with
+// status code 1. The entry is a ReportedErrorEvent so that the error is picked up
+// by Cloud Error Reporting.
+func Fatal(lg Logger, err error) {
+	entry := logging.Entry{Severity: logging.Emergency, Payload: NewReportedErrorEvent(err, 1)}
+	SetupSourceLocation(&entry, 1)
+	logCrash(lg, entry)
+	os.Exit(1)
+}
+
+// Exit flushes the loggers and then exits with the provided status code. Unlike
+// os.Exit(), buffered log entries are not lost.
+func Exit(code int) {
+	FlushLoggers(FlushTimeout)
+	os.Exit(code)
+}
+
+// logCrash synchronously logs entry and flushes the loggers, giving up
+// after FlushTimeout.
+func logCrash(lg Logger, entry logging.Entry) {
+	ctx, cancel := context.WithTimeout(context.Background(), FlushTimeout)
+	defer cancel()
+
+	if lg.Logger != nil {
+		err := lg.Logger.LogSync(ctx, entry)
+		if err != nil {
+			_, _ = fmt.Fprintf(log.Stderr, "gke: failed to log crash: %v\n", err)
+		}
+	}
+
+	deadline, _ := ctx.Deadline()
+	FlushLoggers(time.Until(deadline))
+}
@@ -0,0 +1,108 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func ExampleLogPanics() {
+	rec := gketest.NewRecorder()
+	lg := rec.Logger("example")
+
+	func() {
+		defer func() { fmt.Println("recovered:", recover()) }()
+		defer gke.LogPanics(lg)
+		panic("boom")
+	}()
+
+	e := rec.Entries()[0]
+	ev := e.Payload.(gke.ReportedErrorEvent)
+	fmt.Println(e.Severity, path.Base(e.SourceLocation.File), strings.SplitN(ev.Message, "\n", 2)[0])
+
+	// Output:
+	// recovered: boom
+	// Emergency crash_test.go panic: boom
+}
+
+func TestFlushLoggers(t *testing.T) {
+	var buf strings.Builder
+	lc := gke.NewStandardLogClient(&buf)
+	lg := lc.Logger("test").WithSampling(gke.SamplingConfig{Tick: time.Hour, First: 1})
+
+	for i := 0; i < 3; i++ {
+		lg.Warning("retrying")
+	}
+	gke.FlushLoggers(time.Second)
+	if !strings.Contains(buf.String(), "suppressed 2 log entries") {
+		t.Fatalf("expected FlushLoggers to write the pending report, got %q", buf.String())
+	}
+
+	for i := 0; i < 3; i++ {
+		lg.Warning("retrying again")
+	}
+	if err := lc.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Count(buf.String(), "suppressed 2 log entries") != 2 {
+		t.Fatalf("expected Close to write the pending report, got %q", buf.String())
+	}
+
+	buf.Reset()
+	lg.Warning("closed")
+	lg.Warning("closed")
+	gke.FlushLoggers(time.Second)
+	if strings.Contains(buf.String(), "suppressed") {
+		t.Errorf("expected loggers of a closed client not to be flushed, got %q", buf.String())
+	}
+}
+
+func TestLogPanics_sourceLocationConfig(t *testing.T) {
+	defer gke.SetSourceLocationConfig(gke.SourceLocationConfig{})
+
+	for _, tc := range []struct {
+		cfg  gke.SourceLocationConfig
+		file string
+	}{
+		{gke.SourceLocationConfig{Disabled: true}, ""},
+		{gke.SourceLocationConfig{TrimPaths: true}, "crash_test.go"},
+	} {
+		gke.SetSourceLocationConfig(tc.cfg)
+		rec := gketest.NewRecorder()
+		func() {
+			defer func() { _ = recover() }()
+			defer gke.LogPanics(rec.Logger("test"))
+			panic("boom")
+		}()
+
+		loc := rec.Entries()[0].SourceLocation
+		switch {
+		case tc.file == "" && loc != nil:
+			t.Errorf("expected no source location when capture is disabled, got %v", loc)
+		case tc.file != "" && (loc == nil || loc.File != tc.file):
+			t.Errorf("expected source file %q, got %v", tc.file, loc)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"time"
+
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// WithDedup returns a copy of l that collapses identical entries logged within window,
+// such as a warning logged on every attempt of a retry loop. Entries are identical if
+// their severity, source location and payload match. The first entry is logged
+// immediately. If it is repeated within window, then a single entry is logged at the
+// end of window with the number of repetitions in the "repeated" field and the first
+// and last timestamps in the "firstTimestamp" and "lastTimestamp" fields. Pending
+// reports are written by Flush(), FlushLoggers() and LogClient.Close(). Entries
+// logged with LogSync() are never collapsed.
+//
+//	retryLg := lg.WithDedup(time.Minute)
+//	for attempt := 1; ; attempt++ {
+//		err := connect()
+//		if err == nil {
+//			break
+//		}
+//		retryLg.Warningf("failed to connect: %v", err)
+//	}
+func (l Logger) WithDedup(window time.Duration) Logger {
+	l.Logger = log.NewDedupLogger(l.Logger, window, l.pending)
+	return l
+}
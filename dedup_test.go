@@ -0,0 +1,70 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func ExampleLogger_WithDedup() {
+	rec := gketest.NewRecorder()
+	lg := rec.Logger("example").WithDedup(time.Minute)
+
+	for attempt := 1; attempt <= 5; attempt++ {
+		lg.Warningf("failed to connect: %v", "connection refused")
+	}
+	lg.Info("connected")
+
+	if err := lg.Flush(); err != nil {
+		panic(err)
+	}
+
+	for _, e := range rec.Entries() {
+		if p, ok := e.Payload.(map[string]interface{}); ok {
+			fmt.Println(e.Severity, p["message"], p["repeated"])
+			continue
+		}
+		fmt.Println(e.Severity, e.Payload)
+	}
+
+	// Output:
+	// Warning failed to connect: connection refused
+	// Info connected
+	// Warning failed to connect: connection refused (repeated 4 times) 4
+}
+
+func TestLogger_WithDedup_sourceLocationDisabled(t *testing.T) {
+	gke.SetSourceLocationConfig(gke.SourceLocationConfig{Disabled: true})
+	defer gke.SetSourceLocationConfig(gke.SourceLocationConfig{})
+
+	rec := gketest.NewRecorder()
+	lg := rec.Logger("test").WithDedup(time.Hour)
+
+	lg.Warning("retrying")
+	lg.Warning("retrying")
+	_ = lg.Flush()
+
+	if n := len(rec.Entries()); n != 2 {
+		t.Errorf("expected entries from different call sites not to be collapsed, got %d entries", n)
+	}
+}
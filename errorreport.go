@@ -0,0 +1,169 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"sync"
+)
+
+// ReportedErrorEventType is the @type marker that Cloud Error Reporting uses to
+// recognize error events in structured log entries.
+const ReportedErrorEventType = "type.googleapis.com/google.devtools.clouderrorreporting.v1beta1.ReportedErrorEvent"
+
+// ReportedErrorEvent is a log payload that is recognized by Cloud Error Reporting.
+// See: https://cloud.google.com/error-reporting/docs/formatting-error-messages
+type ReportedErrorEvent struct {
+	Type           string         `json:"@type"`
+	Message        string         `json:"message"`
+	ServiceContext ServiceContext `json:"serviceContext"`
+	Context        ErrorContext   `json:"context"`
+	// Errors is the chain of wrapped errors, starting with the error that was logged.
+	Errors []ErrorChainLink `json:"errors,omitempty"`
+}
+
+// ServiceContext identifies the service that reported an error.
+type ServiceContext struct {
+	Service string `json:"service"`
+	Version string `json:"version,omitempty"`
+}
+
+// ErrorContext describes the context in which an error occurred.
+type ErrorContext struct {
+	ReportLocation ReportLocation `json:"reportLocation"`
+}
+
+// ReportLocation is the location in the source code where the error was reported.
+type ReportLocation struct {
+	FilePath     string `json:"filePath"`
+	LineNumber   int    `json:"lineNumber"`
+	FunctionName string `json:"functionName"`
+}
+
+// ErrorChainLink is a single error in a chain of wrapped errors.
+type ErrorChainLink struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+var (
+	pkgServiceContextOnce sync.Once // protects below
+	pkgServiceContext     ServiceContext
+)
+
+// DefaultServiceContext returns the ServiceContext used for error events. The service
+// name and version are detected from the build info. If there is no build info,
+// then the service name is the base name of the program.
+func DefaultServiceContext() ServiceContext {
+	pkgServiceContextOnce.Do(func() {
+		bi, ok := debug.ReadBuildInfo()
+		if !ok {
+			pkgServiceContext = ServiceContext{Service: path.Base(os.Args[0])}
+			return
+		}
+		pkgServiceContext = ServiceContext{Service: path.Base(bi.Path), Version: bi.Main.Version}
+	})
+	return pkgServiceContext
+}
+
+// NewReportedErrorEvent returns a ReportedErrorEvent for err. The stack trace and
+// report location are captured from the caller of NewReportedErrorEvent. If callDepth
+// is 0, then the caller of NewReportedErrorEvent will be used. If 1, then the caller of
+// that caller, etc, etc. If err is nil, then the message is "nil error".
+func NewReportedErrorEvent(err error, callDepth int) ReportedErrorEvent {
+	var pcs [64]uintptr
+	n := runtime.Callers(2+callDepth, pcs[:])
+	frames := runtime.CallersFrames(pcs[:n])
+
+	var stack strings.Builder
+	if err != nil {
+		stack.WriteString(err.Error())
+	} else {
+		stack.WriteString("nil error")
+	}
+	stack.WriteString("\n\n")
+	stack.WriteString(goroutineHeader())
+	stack.WriteByte('\n')
+
+	var loc ReportLocation
+	for i := 0; ; i++ {
+		f, more := frames.Next()
+		if i == 0 {
+			loc = ReportLocation{FilePath: f.File, LineNumber: f.Line, FunctionName: f.Function}
+		}
+		if f.Function != "" {
+			_, _ = fmt.Fprintf(&stack, "%s(...)\n\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+		if !more {
+			break
+		}
+	}
+
+	return ReportedErrorEvent{
+		Type:           ReportedErrorEventType,
+		Message:        stack.String(),
+		ServiceContext: DefaultServiceContext(),
+		Context:        ErrorContext{ReportLocation: loc},
+		Errors:         errorChain(err),
+	}
+}
+
+// goroutineHeader returns the first line of the current goroutine's stack trace,
+// such as "goroutine 7 [running]:". Error Reporting requires it to parse Go stack traces.
+func goroutineHeader() string {
+	var buf [64]byte
+	n := runtime.Stack(buf[:], false)
+	header := string(buf[:n])
+	if i := strings.IndexByte(header, '\n'); i >= 0 {
+		return header[:i]
+	}
+	return header
+}
+
+// errorChain returns err and each error that it wraps. Errors that wrap
+// multiple errors (Unwrap() []error) are walked depth first.
+func errorChain(err error) (result []ErrorChainLink) {
+	for err != nil {
+		result = append(result, ErrorChainLink{Type: fmt.Sprintf("%T", err), Message: err.Error()})
+
+		if m, ok := err.(interface{ Unwrap() []error }); ok {
+			for _, e := range m.Unwrap() {
+				result = append(result, errorChain(e)...)
+			}
+			return result
+		}
+
+		err = errors.Unwrap(err)
+	}
+	return result
+}
+
+// WithErrorReporting returns a copy of l that logs the errors passed to the *Err
+// methods (e.g. ErrorErr()) as a ReportedErrorEvent so they are picked up by Cloud
+// Error Reporting. Capturing the stack trace is more expensive than formatting the
+// error, so it is disabled by default.
+func (l Logger) WithErrorReporting(enabled bool) Logger {
+	l.errorReporting = enabled
+	return l
+}
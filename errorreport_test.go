@@ -0,0 +1,91 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+type multiError []error
+
+func (m multiError) Error() string   { return fmt.Sprintf("%d errors", len(m)) }
+func (m multiError) Unwrap() []error { return m }
+
+func ExampleLogger_WithErrorReporting() {
+	rec := gketest.NewRecorder()
+	lg := rec.Logger("example").WithErrorReporting(true)
+
+	err := fmt.Errorf("failed to save: %w", multiError{errors.New("disk full"), errors.New("read only")})
+	_ = lg.ErrorErr(err)
+
+	ev := rec.Entries()[0].Payload.(gke.ReportedErrorEvent)
+	fmt.Println(ev.Type == gke.ReportedErrorEventType, ev.Context.ReportLocation.FunctionName)
+	for _, link := range ev.Errors {
+		fmt.Println(link.Type, link.Message)
+	}
+
+	// Output:
+	// true github.com/ajjensen13/gke_test.ExampleLogger_WithErrorReporting
+	// *fmt.wrapError failed to save: 2 errors
+	// gke_test.multiError 2 errors
+	// *errors.errorString disk full
+	// *errors.errorString read only
+}
+
+func TestNewReportedErrorEvent(t *testing.T) {
+	ev := gke.NewReportedErrorEvent(errors.New("boom"), 0)
+
+	if !regexp.MustCompile(`^boom\n\ngoroutine \d+ \[running\]:\ngithub.com/ajjensen13/gke_test.TestNewReportedErrorEvent\(...\)\n\t`).MatchString(ev.Message) {
+		t.Errorf("expected message with goroutine header and stack starting at the caller, got %q", ev.Message)
+	}
+
+	nilEv := gke.NewReportedErrorEvent(nil, 0)
+	if !strings.HasPrefix(nilEv.Message, "nil error\n") || len(nilEv.Errors) != 0 {
+		t.Errorf("expected nil error event, got %+v", nilEv)
+	}
+}
+
+func TestLogger_ErrorErr(t *testing.T) {
+	rec := gketest.NewRecorder()
+	lg := rec.Logger("test")
+
+	_ = lg.ErrorErr(errors.New("plain"))
+	_ = lg.WithErrorReporting(false).ErrorErr(nil)
+
+	es := rec.Entries()
+	if len(es) != 1 || es[0].Payload != "plain" {
+		t.Errorf("expected a single string payload, got %v", es)
+	}
+}
+
+func TestDefaultServiceContext(t *testing.T) {
+	sc := gke.DefaultServiceContext()
+	if sc.Service == "" {
+		t.Errorf("expected a service name, got %+v", sc)
+	}
+	if sc != gke.DefaultServiceContext() {
+		t.Errorf("expected the service context to be cached")
+	}
+}
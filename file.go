@@ -0,0 +1,49 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// FileConfig configures a client returned by NewFileLogClient().
+type FileConfig = log.FileConfig
+
+// NewFileLogClient returns a log client that writes entries to a rotating file,
+// for use with a sidecar-based log collector (e.g. fluent-bit) that tails the file.
+// Files are rotated by size and age, and the file is reopened on SIGHUP so that it
+// can also be rotated by an external tool. Entries are written as text by default,
+// or as newline-delimited JSON if cfg.JSON is set. Entries are not buffered, so
+// Flush() is a no-op and LogSync() also syncs the file to disk.
+//
+//	lc, err := gke.NewFileLogClient(gke.FileConfig{
+//		Path:     "/var/log/app/app.log",
+//		MaxBytes: 100 << 20,
+//		MaxFiles: 5,
+//		Gzip:     true,
+//		JSON:     true,
+//	})
+//	...
+//	defer lc.Close()
+func NewFileLogClient(cfg FileConfig) (LogClient, error) {
+	c, err := log.NewFileClient(cfg)
+	if err != nil {
+		return LogClient{}, err
+	}
+	return newLogClient(c), nil
+}
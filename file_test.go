@@ -0,0 +1,76 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ajjensen13/gke"
+)
+
+func ExampleNewFileLogClient() {
+	dir, err := ioutil.TempDir("", "gke-example")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "app.log")
+	lc, err := gke.NewFileLogClient(gke.FileConfig{Path: path, MaxBytes: 150, MaxFiles: 1, Gzip: true, JSON: true})
+	if err != nil {
+		panic(err)
+	}
+
+	lg := lc.Logger("app")
+	lg.Info("first entry")
+	lg.Info("second entry")
+	lg.Warningw("third entry", "attempt", 3)
+
+	if err := lc.Close(); err != nil {
+		panic(err)
+	}
+
+	rotated, err := filepath.Glob(path + ".*")
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(len(rotated), strings.HasSuffix(rotated[0], ".gz"))
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		panic(err)
+	}
+	var rec struct {
+		LogName     string
+		Severity    string
+		JSONPayload map[string]interface{}
+	}
+	if err := json.Unmarshal(b, &rec); err != nil {
+		panic(err)
+	}
+	fmt.Println(rec.LogName, rec.Severity, rec.JSONPayload)
+
+	// Output:
+	// 1 true
+	// app Warning map[attempt:3 message:third entry]
+}
@@ -0,0 +1,134 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gketest
+
+import (
+	"context"
+	"fmt"
+	"github.com/golang/protobuf/proto"
+	"google.golang.org/api/option"
+	logpb "google.golang.org/genproto/googleapis/logging/v2"
+	"google.golang.org/grpc"
+	"net"
+	"strings"
+	"sync"
+)
+
+// FakeLoggingServer is an in-process fake of the Cloud Logging API. It implements
+// the WriteLogEntries RPC and records the entries written to it. Use
+// gke.NewGkeLogClient() with gke.LogClientAPIOptions(fake.ClientOptions()...)
+// to write to it.
+type FakeLoggingServer struct {
+	logpb.UnimplementedLoggingServiceV2Server
+
+	// Addr is the address the server is listening on.
+	Addr string
+
+	srv      *grpc.Server
+	mu       sync.Mutex // protects below
+	requests []*logpb.WriteLogEntriesRequest
+	writeErr error
+}
+
+// NewFakeLoggingServer starts a new FakeLoggingServer listening on a local port.
+// Close should be called to stop the server.
+func NewFakeLoggingServer() (*FakeLoggingServer, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("gketest: failed to listen: %w", err)
+	}
+
+	result := &FakeLoggingServer{Addr: l.Addr().String(), srv: grpc.NewServer()}
+	logpb.RegisterLoggingServiceV2Server(result.srv, result)
+	go func() { _ = result.srv.Serve(l) }()
+	return result, nil
+}
+
+// ClientOptions returns the options needed for a Cloud Logging client to connect to f.
+func (f *FakeLoggingServer) ClientOptions() []option.ClientOption {
+	return []option.ClientOption{
+		option.WithEndpoint(f.Addr),
+		option.WithoutAuthentication(),
+		option.WithGRPCDialOption(grpc.WithInsecure()),
+	}
+}
+
+// Close stops the server.
+func (f *FakeLoggingServer) Close() {
+	f.srv.Stop()
+}
+
+// WriteLogEntries implements logpb.LoggingServiceV2Server.WriteLogEntries().
+func (f *FakeLoggingServer) WriteLogEntries(_ context.Context, req *logpb.WriteLogEntriesRequest) (*logpb.WriteLogEntriesResponse, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.writeErr != nil {
+		return nil, f.writeErr
+	}
+	f.requests = append(f.requests, proto.Clone(req).(*logpb.WriteLogEntriesRequest))
+	return &logpb.WriteLogEntriesResponse{}, nil
+}
+
+// SetWriteError causes WriteLogEntries to fail with err instead of recording the
+// request. Use a gRPC status error (see status.Error()) to control the code seen
+// by the client. A nil err restores the default behavior.
+func (f *FakeLoggingServer) SetWriteError(err error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.writeErr = err
+}
+
+// Requests returns the requests received so far.
+func (f *FakeLoggingServer) Requests() []*logpb.WriteLogEntriesRequest {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]*logpb.WriteLogEntriesRequest(nil), f.requests...)
+}
+
+// Entries returns the entries received so far for the log with the provided
+// ID (e.g. "my-app"), or all logs if logID is empty. The request level log name,
+// monitored resource and labels are merged into each entry, as the logging service
+// does.
+func (f *FakeLoggingServer) Entries(logID string) []*logpb.LogEntry {
+	var result []*logpb.LogEntry
+	for _, req := range f.Requests() {
+		for _, e := range req.Entries {
+			e := proto.Clone(e).(*logpb.LogEntry)
+			if e.LogName == "" {
+				e.LogName = req.LogName
+			}
+			if e.Resource == nil {
+				e.Resource = req.Resource
+			}
+			if len(req.Labels) > 0 {
+				labels := make(map[string]string, len(req.Labels)+len(e.Labels))
+				for k, v := range req.Labels {
+					labels[k] = v
+				}
+				for k, v := range e.Labels {
+					labels[k] = v
+				}
+				e.Labels = labels
+			}
+			if logID == "" || strings.HasSuffix(e.LogName, "/logs/"+logID) {
+				result = append(result, e)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,143 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gketest_test
+
+import (
+	"context"
+	"fmt"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func ExampleFakeLoggingServer() {
+	fake, err := gketest.NewFakeLoggingServer()
+	if err != nil {
+		panic(err)
+	}
+	defer fake.Close()
+
+	md := &gke.MetadataType{
+		ProjectID:     "my-project",
+		ClusterName:   "my-cluster",
+		Zone:          "us-central1-a",
+		PodName:       "web-0",
+		PodNamespace:  "default",
+		PodLabels:     map[string]string{"app.kubernetes.io/name": "web"},
+		ContainerName: "web",
+	}
+
+	lc, cleanup, err := gke.NewGkeLogClient(context.Background(), md, gke.LogClientAPIOptions(fake.ClientOptions()...))
+	if err != nil {
+		panic(err)
+	}
+	defer cleanup()
+
+	lg := lc.Logger("web")
+	lg.Warning("disk almost full")
+	if err := lg.Flush(); err != nil {
+		panic(err)
+	}
+
+	for _, e := range fake.Entries("web") {
+		fmt.Println(e.LogName, e.Severity, e.GetTextPayload())
+		fmt.Println(e.Resource.Type, e.Resource.Labels["pod_name"], e.Resource.Labels["namespace_name"])
+		fmt.Println(e.Labels)
+	}
+
+	// Output:
+	// projects/my-project/logs/web WARNING disk almost full
+	// k8s_container web-0 default
+	// map[k8s-pod/app_kubernetes_io/name:web]
+}
+
+func TestFakeLoggingServer_unserializablePayload(t *testing.T) {
+	fake, err := gketest.NewFakeLoggingServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fake.Close()
+
+	md := &gke.MetadataType{ProjectID: "my-project"}
+	lc, cleanup, err := gke.NewGkeLogClient(context.Background(), md, gke.LogClientAPIOptions(fake.ClientOptions()...))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	lg := lc.Logger("web")
+	lg.Info(make(chan int))
+	lg.Info(map[string]interface{}{"status": 200})
+	if err := lg.Flush(); err != nil {
+		t.Fatal(err)
+	}
+
+	es := fake.Entries("web")
+	if len(es) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(es))
+	}
+	if got := es[0].GetTextPayload(); !strings.HasPrefix(got, "failed to serialize payload of type chan int") {
+		t.Errorf("expected a text entry describing the failure, got %q", got)
+	}
+	if got := es[1].GetJsonPayload().GetFields()["status"].GetNumberValue(); got != 200 {
+		t.Errorf("expected JSON payload with status 200, got %v", es[1].GetJsonPayload())
+	}
+}
+
+func TestLogClientOnError(t *testing.T) {
+	fake, err := gketest.NewFakeLoggingServer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer fake.Close()
+
+	errc := make(chan error, 10)
+	md := &gke.MetadataType{ProjectID: "my-project"}
+	lc, cleanup, err := gke.NewGkeLogClient(context.Background(), md,
+		gke.LogClientAPIOptions(fake.ClientOptions()...),
+		gke.LogClientOnError(func(err error) { errc <- err }),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	before := gke.LogMetricsSnapshot().WriteErrors
+	fake.SetWriteError(status.Error(codes.InvalidArgument, "rejected"))
+
+	lg := lc.Logger("web")
+	lg.Info("rejected entry")
+	_ = lg.Flush()
+
+	select {
+	case err := <-errc:
+		if status.Code(err) != codes.InvalidArgument {
+			t.Errorf("expected the write error to be passed to the handler, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the error handler to be called")
+	}
+	if got := gke.LogMetricsSnapshot().WriteErrors - before; got != 1 {
+		t.Errorf("expected 1 write error to be counted, got %d", got)
+	}
+}
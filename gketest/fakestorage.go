@@ -0,0 +1,138 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gketest
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// FakeStorageServer is an in-process fake of the Cloud Storage JSON API. It
+// only supports multipart object uploads, which the storage client uses for
+// objects smaller than its chunk size (16 MiB by default). Set the
+// STORAGE_EMULATOR_HOST environment variable to f.Host before calling
+// gke.NewStorageClient() to write to it.
+type FakeStorageServer struct {
+	// Host is the host and port the server is listening on.
+	Host string
+
+	srv     *httptest.Server
+	mu      sync.Mutex // protects below
+	objects map[string]FakeObject
+}
+
+// FakeObject is an object uploaded to a FakeStorageServer.
+type FakeObject struct {
+	Bucket          string
+	Name            string
+	ContentType     string
+	ContentEncoding string
+	Content         []byte
+}
+
+// NewFakeStorageServer starts a new FakeStorageServer listening on a local port.
+// Close should be called to stop the server.
+func NewFakeStorageServer() *FakeStorageServer {
+	result := &FakeStorageServer{objects: make(map[string]FakeObject)}
+	result.srv = httptest.NewServer(http.HandlerFunc(result.serveHTTP))
+	result.Host = strings.TrimPrefix(result.srv.URL, "http://")
+	return result
+}
+
+// Close stops the server.
+func (f *FakeStorageServer) Close() {
+	f.srv.Close()
+}
+
+// Objects returns the objects uploaded so far, in no particular order.
+func (f *FakeStorageServer) Objects() []FakeObject {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	result := make([]FakeObject, 0, len(f.objects))
+	for _, o := range f.objects {
+		result = append(result, o)
+	}
+	return result
+}
+
+func (f *FakeStorageServer) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	const prefix = "/upload/storage/v1/b/"
+	if r.Method != http.MethodPost || !strings.HasPrefix(r.URL.Path, prefix) || r.URL.Query().Get("uploadType") != "multipart" {
+		http.Error(w, "gketest: unsupported request: "+r.Method+" "+r.URL.String(), http.StatusNotImplemented)
+		return
+	}
+	bucket := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, prefix), "/o")
+
+	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	mr := multipart.NewReader(r.Body, params["boundary"])
+
+	obj := FakeObject{Bucket: bucket}
+	for i := 0; i < 2; i++ {
+		p, err := mr.NextPart()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		b, err := ioutil.ReadAll(p)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if i == 0 {
+			var md struct {
+				Name            string `json:"name"`
+				ContentType     string `json:"contentType"`
+				ContentEncoding string `json:"contentEncoding"`
+			}
+			if err := json.Unmarshal(b, &md); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			obj.Name, obj.ContentType, obj.ContentEncoding = md.Name, md.ContentType, md.ContentEncoding
+		} else {
+			obj.Content = b
+		}
+	}
+
+	f.mu.Lock()
+	f.objects[obj.Bucket+"/"+obj.Name] = obj
+	f.mu.Unlock()
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]string{
+		"kind":            "storage#object",
+		"bucket":          obj.Bucket,
+		"name":            obj.Name,
+		"contentType":     obj.ContentType,
+		"contentEncoding": obj.ContentEncoding,
+		"size":            strconv.Itoa(len(obj.Content)),
+	})
+}
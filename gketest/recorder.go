@@ -0,0 +1,231 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+// Package gketest provides utilities for testing code that uses package gke.
+package gketest
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"encoding/json"
+	"fmt"
+	stdlog "log"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// Entry is a log entry captured by a Recorder.
+type Entry struct {
+	// LogID is the ID of the log the entry was written to.
+	LogID string
+	logging.Entry
+}
+
+// PayloadString returns the payload formatted as a string. String payloads are
+// returned as is. Other payloads are JSON encoded if possible.
+func (e Entry) PayloadString() string {
+	switch p := e.Payload.(type) {
+	case string:
+		return p
+	case nil:
+		return ""
+	}
+	b, err := json.Marshal(e.Payload)
+	if err != nil {
+		return fmt.Sprintf("%v", e.Payload)
+	}
+	return string(b)
+}
+
+// Recorder captures log entries in memory so that tests can make assertions
+// about what was logged. A Recorder is safe for concurrent use.
+type Recorder struct {
+	mu      sync.Mutex // protects below
+	entries []Entry
+	changed chan struct{}
+}
+
+// NewRecorder returns a new Recorder.
+func NewRecorder() *Recorder {
+	return &Recorder{changed: make(chan struct{})}
+}
+
+// LogClient returns a gke.LogClient that writes entries to r.
+func (r *Recorder) LogClient() gke.LogClient {
+	return gke.LogClient{Client: recorderClient{r}}
+}
+
+// Logger is equivalent to r.LogClient().Logger(logID).
+func (r *Recorder) Logger(logID string) gke.Logger {
+	return r.LogClient().Logger(logID)
+}
+
+func (r *Recorder) record(e Entry) {
+	if e.Timestamp.IsZero() {
+		e.Timestamp = time.Now()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = append(r.entries, e)
+	close(r.changed)
+	r.changed = make(chan struct{})
+}
+
+// Entries returns a copy of the entries recorded so far.
+func (r *Recorder) Entries() []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Entry(nil), r.entries...)
+}
+
+// Reset discards the entries recorded so far.
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries = nil
+}
+
+// Find returns the recorded entries that match all of the matchers.
+//
+//	errs := rec.Find(gketest.MinSeverity(logging.Error), gketest.Contains("connection refused"))
+func (r *Recorder) Find(matchers ...Matcher) []Entry {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return find(r.entries, matchers)
+}
+
+// WaitFor blocks until an entry that matches all of the matchers has been recorded
+// or ctx is done. The first matching entry is returned.
+func (r *Recorder) WaitFor(ctx context.Context, matchers ...Matcher) (Entry, error) {
+	for {
+		r.mu.Lock()
+		found := find(r.entries, matchers)
+		changed := r.changed
+		r.mu.Unlock()
+
+		if len(found) > 0 {
+			return found[0], nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return Entry{}, fmt.Errorf("gketest: no matching entry was recorded: %w", ctx.Err())
+		}
+	}
+}
+
+func find(entries []Entry, matchers []Matcher) (result []Entry) {
+	for _, e := range entries {
+		if matchAll(e, matchers) {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+func matchAll(e Entry, matchers []Matcher) bool {
+	for _, m := range matchers {
+		if !m(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matcher reports whether an entry matches some criteria.
+type Matcher func(e Entry) bool
+
+// MinSeverity matches entries with a severity of at least severity.
+func MinSeverity(severity logging.Severity) Matcher {
+	return func(e Entry) bool { return e.Severity >= severity }
+}
+
+// Severity matches entries with exactly the provided severity.
+func Severity(severity logging.Severity) Matcher {
+	return func(e Entry) bool { return e.Severity == severity }
+}
+
+// Contains matches entries whose payload string contains substr. See Entry.PayloadString().
+func Contains(substr string) Matcher {
+	return func(e Entry) bool { return strings.Contains(e.PayloadString(), substr) }
+}
+
+// LogID matches entries written to the log with the provided ID.
+func LogID(logID string) Matcher {
+	return func(e Entry) bool { return e.LogID == logID }
+}
+
+// Label matches entries with a label key set to value.
+func Label(key, value string) Matcher {
+	return func(e Entry) bool {
+		v, ok := e.Labels[key]
+		return ok && v == value
+	}
+}
+
+// Trace matches entries that are part of the trace with the provided name.
+func Trace(trace string) Matcher {
+	return func(e Entry) bool { return e.Trace == trace }
+}
+
+type recorderClient struct {
+	r *Recorder
+}
+
+// Logger implements log.Client.Logger().
+func (c recorderClient) Logger(logID string) log.Logger {
+	return recorderLogger{c.r, logID}
+}
+
+// Close implements log.Client.Close().
+func (c recorderClient) Close() error {
+	return nil
+}
+
+type recorderLogger struct {
+	r     *Recorder
+	logID string
+}
+
+// StandardLogger implements log.Logger.StandardLogger().
+func (l recorderLogger) StandardLogger(severity logging.Severity) *stdlog.Logger {
+	return log.NewStandardLogger(l, severity)
+}
+
+// Log implements log.Logger.Log().
+func (l recorderLogger) Log(entry logging.Entry) {
+	log.SetupSourceLocation(&entry, 1)
+	l.r.record(Entry{l.logID, entry})
+}
+
+// Flush implements log.Logger.Flush().
+func (l recorderLogger) Flush() error {
+	return nil
+}
+
+// LogSync implements log.Logger.LogSync().
+func (l recorderLogger) LogSync(_ context.Context, entry logging.Entry) error {
+	log.SetupSourceLocation(&entry, 1)
+	l.r.record(Entry{l.logID, entry})
+	return nil
+}
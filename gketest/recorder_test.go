@@ -0,0 +1,62 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gketest_test
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func ExampleRecorder() {
+	rec := gketest.NewRecorder()
+	lg := rec.Logger("example")
+
+	lg.Info("starting")
+	lg.ErrorErr(errors.New("connection refused"))
+
+	for _, e := range rec.Find(gketest.MinSeverity(logging.Error), gketest.Contains("refused")) {
+		fmt.Println(e.LogID, e.Severity, e.PayloadString())
+	}
+
+	// Output:
+	// example Error connection refused
+}
+
+func ExampleRecorder_WaitFor() {
+	rec := gketest.NewRecorder()
+	lg := rec.Logger("example")
+
+	go lg.Noticef("finished after %v", time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	e, err := rec.WaitFor(ctx, gketest.Severity(logging.Notice))
+	if err != nil {
+		panic(err)
+	}
+	fmt.Println(e.PayloadString())
+
+	// Output:
+	// finished after 1ms
+}
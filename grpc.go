@@ -0,0 +1,310 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"fmt"
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// RequestIDHeader is the header (or gRPC metadata key) used to propagate request IDs.
+	RequestIDHeader = "x-request-id"
+	// RequestIDLabel is the label used for request IDs on entries logged with Logger.WithContext().
+	RequestIDLabel = "request_id"
+)
+
+type requestIDKey struct{}
+
+// NewRequestID returns a randomly generated request ID.
+func NewRequestID() string {
+	return uuid.New().String()
+}
+
+// WithRequestID returns a copy of ctx that carries the request ID id.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestIDFromContext returns the request ID stored in ctx, if any.
+func RequestIDFromContext(ctx context.Context) (id string, ok bool) {
+	id, ok = ctx.Value(requestIDKey{}).(string)
+	return
+}
+
+type loggerKey struct{}
+
+// ContextWithLogger returns a copy of ctx that carries lg.
+func ContextWithLogger(ctx context.Context, lg Logger) context.Context {
+	return context.WithValue(ctx, loggerKey{}, lg)
+}
+
+// LoggerFromContext returns the Logger stored in ctx, if any. Handlers called by the
+// gRPC interceptors in this package (e.g. UnaryServerInterceptor()) receive a context
+// with a Logger that stamps each entry with the request ID and trace context of the RPC.
+func LoggerFromContext(ctx context.Context) (lg Logger, ok bool) {
+	lg, ok = ctx.Value(loggerKey{}).(Logger)
+	return
+}
+
+// GRPCCodeSeverity returns the severity used to log an RPC that completed with code.
+// It is Info for OK, Warning for codes that usually indicate a problem with the
+// request (e.g. InvalidArgument or NotFound) and Error for codes that usually
+// indicate a problem with the server (e.g. Internal or Unavailable).
+func GRPCCodeSeverity(code codes.Code) logging.Severity {
+	switch code {
+	case codes.OK:
+		return logging.Info
+	case codes.Canceled, codes.InvalidArgument, codes.NotFound, codes.AlreadyExists,
+		codes.PermissionDenied, codes.Unauthenticated, codes.ResourceExhausted,
+		codes.FailedPrecondition, codes.Aborted, codes.OutOfRange:
+		return logging.Warning
+	default:
+		return logging.Error
+	}
+}
+
+// UnaryServerInterceptor returns an interceptor that sets up the request ID and trace
+// context of each RPC, stores a Logger for it in the handler context (see LoggerFromContext())
+// and logs the RPC to lg with its method, status code and latency once it completes.
+//
+// The request ID is taken from the RequestIDHeader metadata or generated, and is sent
+// back to the client in the response header. The trace context is taken from the
+// TraceparentHeader or CloudTraceContextHeader metadata or generated.
+//
+//	srv := grpc.NewServer(
+//		grpc.UnaryInterceptor(gke.UnaryServerInterceptor(lg)),
+//		grpc.StreamInterceptor(gke.StreamServerInterceptor(lg)),
+//	)
+func UnaryServerInterceptor(lg Logger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		ctx, rl := setupServerContext(ctx, lg)
+		_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, requestID(ctx)))
+
+		resp, err := handler(ctx, req)
+		logRPC(ctx, rl, "server", info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+// StreamServerInterceptor returns the streaming equivalent of UnaryServerInterceptor().
+// The RPC is logged once the stream handler returns.
+func StreamServerInterceptor(lg Logger) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		ctx, rl := setupServerContext(ss.Context(), lg)
+		_ = ss.SetHeader(metadata.Pairs(RequestIDHeader, requestID(ctx)))
+
+		err := handler(srv, serverStream{ss, ctx})
+		logRPC(ctx, rl, "server", info.FullMethod, start, err)
+		return err
+	}
+}
+
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context implements grpc.ServerStream.Context().
+func (s serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func setupServerContext(ctx context.Context, lg Logger) (context.Context, Logger) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if _, ok := RequestIDFromContext(ctx); !ok {
+		id := firstMetadataValue(md, RequestIDHeader)
+		if id == "" {
+			id = NewRequestID()
+		}
+		ctx = WithRequestID(ctx, id)
+	}
+
+	if _, ok := TraceContextFromContext(ctx); !ok {
+		h := http.Header{}
+		h.Set(TraceparentHeader, firstMetadataValue(md, TraceparentHeader))
+		h.Set(CloudTraceContextHeader, firstMetadataValue(md, CloudTraceContextHeader))
+		tc, ok := ParseTraceContext(h)
+		if !ok {
+			tc = NewTraceContext()
+		}
+		ctx = WithTraceContext(ctx, tc)
+	}
+
+	rl := lg.WithContext(ctx)
+	return ContextWithLogger(ctx, rl), rl
+}
+
+// UnaryClientInterceptor returns an interceptor that propagates the request ID and trace
+// context of ctx to the server, generating them if ctx does not have them, and logs each
+// RPC to lg with its method, status code and latency once it completes. Each RPC is sent
+// as a new child span of the trace context (see TraceContext.NewSpan()).
+//
+//	conn, err := grpc.Dial(target,
+//		grpc.WithUnaryInterceptor(gke.UnaryClientInterceptor(lg)),
+//		grpc.WithStreamInterceptor(gke.StreamClientInterceptor(lg)),
+//	)
+func UnaryClientInterceptor(lg Logger) grpc.UnaryClientInterceptor {
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		start := time.Now()
+		ctx, rl := setupClientContext(ctx, lg)
+
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		logRPC(ctx, rl, "client", method, start, err)
+		return err
+	}
+}
+
+// StreamClientInterceptor returns the streaming equivalent of UnaryClientInterceptor().
+// The RPC is logged once the stream fails to be created, the response of a
+// client-streaming RPC is received, receiving from the stream fails (including with
+// io.EOF at the end of the stream), sending to the stream fails, or ctx is done,
+// such as when the caller cancels the stream instead of receiving until the end.
+func StreamClientInterceptor(lg Logger) grpc.StreamClientInterceptor {
+	return func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+		start := time.Now()
+		ctx, rl := setupClientContext(ctx, lg)
+
+		cs, err := streamer(ctx, desc, cc, method, opts...)
+		if err != nil {
+			logRPC(ctx, rl, "client", method, start, err)
+			return nil, err
+		}
+
+		result := &clientStream{ClientStream: cs, serverStreams: desc.ServerStreams, finished: make(chan struct{}), done: func(err error) {
+			logRPC(ctx, rl, "client", method, start, err)
+		}}
+		go result.watch(ctx)
+		return result, nil
+	}
+}
+
+type clientStream struct {
+	grpc.ClientStream
+	serverStreams bool
+	once          sync.Once
+	finished      chan struct{} // closed once the RPC has been logged
+	done          func(err error)
+}
+
+// finish logs the RPC with err unless it has already been logged.
+func (c *clientStream) finish(err error) {
+	c.once.Do(func() {
+		close(c.finished)
+		c.done(err)
+	})
+}
+
+// watch logs the RPC if ctx is done before the stream finishes, such as when
+// the caller cancels the stream without receiving until the end.
+func (c *clientStream) watch(ctx context.Context) {
+	select {
+	case <-ctx.Done():
+		c.finish(status.FromContextError(ctx.Err()).Err())
+	case <-c.finished:
+	}
+}
+
+// SendMsg implements grpc.ClientStream.SendMsg().
+func (c *clientStream) SendMsg(m interface{}) error {
+	err := c.ClientStream.SendMsg(m)
+	if err != nil && err != io.EOF {
+		c.finish(err)
+	}
+	return err
+}
+
+// RecvMsg implements grpc.ClientStream.RecvMsg().
+func (c *clientStream) RecvMsg(m interface{}) error {
+	err := c.ClientStream.RecvMsg(m)
+	switch {
+	case err == io.EOF:
+		c.finish(nil)
+	case err != nil:
+		c.finish(err)
+	case !c.serverStreams:
+		c.finish(nil) // the single response of a client-streaming RPC
+	}
+	return err
+}
+
+func setupClientContext(ctx context.Context, lg Logger) (context.Context, Logger) {
+	id, ok := RequestIDFromContext(ctx)
+	if !ok {
+		id = NewRequestID()
+		ctx = WithRequestID(ctx, id)
+	}
+
+	tc, ok := TraceContextFromContext(ctx)
+	if ok {
+		tc = tc.NewSpan()
+	} else {
+		tc = NewTraceContext()
+	}
+	ctx = WithTraceContext(ctx, tc)
+
+	ctx = metadata.AppendToOutgoingContext(ctx, RequestIDHeader, id, TraceparentHeader, tc.Traceparent())
+	return ctx, lg.WithContext(ctx)
+}
+
+func requestID(ctx context.Context) string {
+	id, _ := RequestIDFromContext(ctx)
+	return id
+}
+
+func firstMetadataValue(md metadata.MD, key string) string {
+	if vs := md.Get(key); len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+func logRPC(ctx context.Context, lg Logger, kind, method string, start time.Time, err error) {
+	code := status.Code(err)
+	payload := map[string]interface{}{
+		"message": fmt.Sprintf("%s %s", method, code),
+		"kind":    kind,
+		"method":  method,
+		"code":    code.String(),
+		"latency": time.Since(start).String(),
+	}
+	if err != nil {
+		payload["error"] = status.Convert(err).Message()
+	}
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		payload["peer"] = p.Addr.String()
+	}
+
+	entry := logging.Entry{Severity: GRPCCodeSeverity(code), Payload: payload}
+	SetupSourceLocation(&entry, 1)
+	lg.Log(entry)
+}
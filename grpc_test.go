@@ -0,0 +1,266 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	testpb "google.golang.org/grpc/interop/grpc_testing"
+	"google.golang.org/grpc/status"
+	"io"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	rec := gketest.NewRecorder()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(
+		grpc.UnaryInterceptor(gke.UnaryServerInterceptor(rec.Logger("server"))),
+		grpc.StreamInterceptor(gke.StreamServerInterceptor(rec.Logger("server"))),
+	)
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(gke.UnaryClientInterceptor(rec.Logger("client"))),
+		grpc.WithStreamInterceptor(gke.StreamClientInterceptor(rec.Logger("client"))),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	client := healthpb.NewHealthClient(conn)
+
+	ctx := gke.WithRequestID(context.Background(), "req-1")
+	if _, err := client.Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "missing"})
+	if status.Code(err) != codes.NotFound {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+
+	const method = "/grpc.health.v1.Health/Check"
+	for _, tc := range []struct {
+		logID    string
+		severity logging.Severity
+	}{
+		{"server", logging.Info},
+		{"client", logging.Info},
+		{"server", logging.Warning},
+		{"client", logging.Warning},
+	} {
+		es := rec.Find(gketest.LogID(tc.logID), gketest.Severity(tc.severity), gketest.Contains(method))
+		if len(es) != 1 {
+			t.Fatalf("expected 1 %s %v entry, got %d: %v", tc.logID, tc.severity, len(es), rec.Entries())
+		}
+	}
+
+	server := rec.Find(gketest.LogID("server"), gketest.Label(gke.RequestIDLabel, "req-1"))
+	client1 := rec.Find(gketest.LogID("client"), gketest.Label(gke.RequestIDLabel, "req-1"))
+	if len(server) != 1 || len(client1) != 1 {
+		t.Fatalf("expected request ID to be propagated, got %v", rec.Entries())
+	}
+	if server[0].Trace == "" || server[0].Trace != client1[0].Trace {
+		t.Errorf("expected trace to be propagated, got %q and %q", client1[0].Trace, server[0].Trace)
+	}
+
+	missing := rec.Find(gketest.LogID("server"), gketest.Severity(logging.Warning))[0]
+	if id := missing.Labels[gke.RequestIDLabel]; id == "" || id == "req-1" {
+		t.Errorf("expected a generated request ID, got %q", id)
+	}
+}
+
+func TestUnaryClientInterceptor_childSpan(t *testing.T) {
+	rec := gketest.NewRecorder()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.UnaryInterceptor(gke.UnaryServerInterceptor(rec.Logger("server"))))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure(),
+		grpc.WithUnaryInterceptor(gke.UnaryClientInterceptor(rec.Logger("client"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// A trace context parsed from X-Cloud-Trace-Context may not have a span ID.
+	for _, parent := range []gke.TraceContext{
+		{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+		{TraceID: "105445aa7843bc8bf206b12000100000", Sampled: true},
+	} {
+		ctx := gke.WithTraceContext(context.Background(), parent)
+		if _, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{}); err != nil {
+			t.Fatal(err)
+		}
+
+		server := rec.Find(gketest.LogID("server"), gketest.Trace(parent.TraceName()))
+		client := rec.Find(gketest.LogID("client"), gketest.Trace(parent.TraceName()))
+		if len(server) != 1 || len(client) != 1 {
+			t.Fatalf("expected the trace of %+v to be propagated, got %v", parent, rec.Entries())
+		}
+		if server[0].SpanID == "" || server[0].SpanID == parent.SpanID || server[0].SpanID != client[0].SpanID {
+			t.Errorf("expected a new child span of %+v, got client span %q and server span %q", parent, client[0].SpanID, server[0].SpanID)
+		}
+	}
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	rec := gketest.NewRecorder()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer(grpc.StreamInterceptor(gke.StreamServerInterceptor(rec.Logger("server"))))
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure(),
+		grpc.WithStreamInterceptor(gke.StreamClientInterceptor(rec.Logger("client"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	ctx, cancel := context.WithCancel(gke.WithRequestID(context.Background(), "req-2"))
+	stream, err := healthpb.NewHealthClient(conn).Watch(ctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if _, err := stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Fatalf("expected Canceled, got %v", err)
+	}
+
+	wctx, wcancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer wcancel()
+	for _, logID := range []string{"client", "server"} {
+		_, err := rec.WaitFor(wctx, gketest.LogID(logID), gketest.Label(gke.RequestIDLabel, "req-2"), gketest.Contains("/grpc.health.v1.Health/Watch Canceled"))
+		if err != nil {
+			t.Fatalf("expected %s entry for canceled stream: %v: %v", logID, err, rec.Entries())
+		}
+	}
+}
+
+type streamingInputServer struct {
+	testpb.UnimplementedTestServiceServer
+}
+
+func (streamingInputServer) StreamingInputCall(stream testpb.TestService_StreamingInputCallServer) error {
+	size := 0
+	for {
+		req, err := stream.Recv()
+		if err == io.EOF {
+			return stream.SendAndClose(&testpb.StreamingInputCallResponse{AggregatedPayloadSize: int32(size)})
+		}
+		if err != nil {
+			return err
+		}
+		size += len(req.GetPayload().GetBody())
+	}
+}
+
+func TestStreamClientInterceptor(t *testing.T) {
+	rec := gketest.NewRecorder()
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := grpc.NewServer()
+	testpb.RegisterTestServiceServer(srv, streamingInputServer{})
+	healthpb.RegisterHealthServer(srv, health.NewServer())
+	go func() { _ = srv.Serve(lis) }()
+	defer srv.Stop()
+
+	conn, err := grpc.Dial(lis.Addr().String(), grpc.WithInsecure(),
+		grpc.WithStreamInterceptor(gke.StreamClientInterceptor(rec.Logger("client"))))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	// A client-streaming RPC ends with a single response instead of io.EOF.
+	ctx := gke.WithRequestID(context.Background(), "req-3")
+	stream, err := testpb.NewTestServiceClient(conn).StreamingInputCall(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, body := range []string{"a", "bc"} {
+		if err := stream.Send(&testpb.StreamingInputCallRequest{Payload: &testpb.Payload{Body: []byte(body)}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.GetAggregatedPayloadSize() != 3 {
+		t.Errorf("expected aggregated size 3, got %d", resp.GetAggregatedPayloadSize())
+	}
+
+	// The caller stops receiving and cancels the stream before it ends.
+	wctx, cancel := context.WithCancel(gke.WithRequestID(context.Background(), "req-4"))
+	watch, err := healthpb.NewHealthClient(conn).Watch(wctx, &healthpb.HealthCheckRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := watch.Recv(); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+
+	tctx, tcancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer tcancel()
+	for _, tc := range []struct{ id, msg string }{
+		{"req-3", "/grpc.testing.TestService/StreamingInputCall OK"},
+		{"req-4", "/grpc.health.v1.Health/Watch Canceled"},
+	} {
+		if _, err := rec.WaitFor(tctx, gketest.LogID("client"), gketest.Label(gke.RequestIDLabel, tc.id), gketest.Contains(tc.msg)); err != nil {
+			t.Fatalf("expected client entry %q: %v: %v", tc.msg, err, rec.Entries())
+		}
+	}
+	if n := len(rec.Find(gketest.LogID("client"), gketest.Label(gke.RequestIDLabel, "req-3"))); n != 1 {
+		t.Errorf("expected the client-streaming RPC to be logged once, got %d entries", n)
+	}
+}
@@ -20,11 +20,19 @@ package log
 import (
 	"cloud.google.com/go/logging"
 	"context"
-	logpb "google.golang.org/genproto/googleapis/logging/v2"
+	"io"
 	"log"
+	"os"
 	"runtime"
+	"sync"
 )
 
+// Stderr is the original os.Stderr, captured when the package is initialized.
+// Errors that cannot be logged are written to it rather than os.Stderr, which
+// may since have been replaced with a pipe that feeds back into a client (see
+// gke.CaptureStderr()).
+var Stderr io.Writer = os.Stderr
+
 // Client is used to provision new loggers and close underlying connections during shutdown.
 type Client interface {
 	// Logger returns a logger with a provided logID.
@@ -47,16 +55,84 @@ type Logger interface {
 
 // SetupSourceLocation sets up the entry.SourceLocation field if it is not already set. If callDepth is 0, then
 // the source location of the caller to SetupSourceLocation will be used. If 1, then the caller of that caller, etc, etc.
+// Functions registered with RegisterHelper() are skipped. Capture is configured with SetSourceLocationConfig().
 func SetupSourceLocation(entry *logging.Entry, callDepth int) {
 	if entry.SourceLocation != nil {
 		return
 	}
 
-	var cis [1]uintptr
-	c := runtime.Callers(2+callDepth, cis[:])
-	if c > 0 {
-		fs := runtime.CallersFrames(cis[:])
-		f, _ := fs.Next()
-		entry.SourceLocation = &logpb.LogEntrySourceLocation{File: f.File, Line: int64(f.Line), Function: f.Function}
+	cfg := sourceLocationConfig()
+	if cfg.Disabled {
+		return
+	}
+
+	if !hasHelpers() {
+		var cis [1]uintptr
+		c := runtime.Callers(2+callDepth, cis[:])
+		if c > 0 {
+			fs := runtime.CallersFrames(cis[:])
+			f, _ := fs.Next()
+			setSourceLocation(entry, f, 0, 2+callDepth, cfg)
+		}
+		return
+	}
+
+	var pcs [32]uintptr
+	n := runtime.Callers(2+callDepth, pcs[:])
+	fs := runtime.CallersFrames(pcs[:n])
+	for skipped := 0; ; skipped++ {
+		f, more := fs.Next()
+		if !more || !isHelper(f.Function) {
+			setSourceLocation(entry, f, skipped, 2+callDepth, cfg)
+			return
+		}
+	}
+}
+
+// LoggerSet is a set of loggers that is safe for concurrent use. Loggers
+// returned by NewDedupLogger() and NewSamplingLogger() add themselves to a
+// LoggerSet while they hold pending reports, so that the reports can be
+// flushed before the process exits. Methods on a nil *LoggerSet are no-ops.
+type LoggerSet struct {
+	mu sync.Mutex // protects below
+	ls map[Logger]struct{}
+}
+
+// NewLoggerSet returns an empty LoggerSet.
+func NewLoggerSet() *LoggerSet {
+	return &LoggerSet{ls: make(map[Logger]struct{})}
+}
+
+// Add adds l to the set. l must be comparable.
+func (s *LoggerSet) Add(l Logger) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ls[l] = struct{}{}
+}
+
+// Remove removes l from the set.
+func (s *LoggerSet) Remove(l Logger) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.ls, l)
+}
+
+// List returns the loggers in the set.
+func (s *LoggerSet) List() []Logger {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]Logger, 0, len(s.ls))
+	for l := range s.ls {
+		result = append(result, l)
 	}
+	return result
 }
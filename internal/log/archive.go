@@ -0,0 +1,336 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"bytes"
+	"cloud.google.com/go/logging"
+	"cloud.google.com/go/storage"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"path"
+	"sync"
+	"time"
+)
+
+// ArchiveConfig configures an archive client. See NewArchiveClient().
+type ArchiveConfig struct {
+	// Prefix is prepended to the name of each object (e.g. "audit/").
+	Prefix string
+	// MaxObjectBytes is the uncompressed size at which an object is written
+	// and a new one is started. If 0, then 8 MiB is used.
+	MaxObjectBytes int
+	// MaxObjectAge is the maximum amount of time that entries are buffered
+	// before an object is written. If 0, then 5 minutes is used.
+	MaxObjectAge time.Duration
+	// Gzip causes objects to be gzip compressed.
+	Gzip bool
+}
+
+const (
+	defaultArchiveObjectBytes = 8 << 20
+	defaultArchiveObjectAge   = 5 * time.Minute
+)
+
+// ErrArchiveClosed is returned when an entry is logged to an archive client
+// that has been closed.
+var ErrArchiveClosed = errors.New("archive client is closed")
+
+// NewArchiveClient returns a client that batches entries into newline-delimited
+// JSON objects in bucket. Objects are named PREFIX/LOG_ID/YYYY/MM/DD/HHMMSS.NNNNNNNNN.ndjson
+// (with a .gz suffix if compressed), partitioned by the UTC date of their entries.
+// An object is written when it reaches cfg.MaxObjectBytes, is older than cfg.MaxObjectAge,
+// the date changes or the logger is flushed. Close writes any buffered entries.
+//
+// The context is used for writing objects and should remain open for the life of the client.
+func NewArchiveClient(ctx context.Context, bucket *storage.BucketHandle, cfg ArchiveConfig) Client {
+	if cfg.MaxObjectBytes <= 0 {
+		cfg.MaxObjectBytes = defaultArchiveObjectBytes
+	}
+	if cfg.MaxObjectAge <= 0 {
+		cfg.MaxObjectAge = defaultArchiveObjectAge
+	}
+
+	result := &archiveClient{ctx: ctx, bucket: bucket, cfg: cfg, done: make(chan struct{})}
+	result.wg.Add(1)
+	go result.rollover()
+	return result
+}
+
+type archiveClient struct {
+	ctx    context.Context
+	bucket *storage.BucketHandle
+	cfg    ArchiveConfig
+	done   chan struct{}
+	wg     sync.WaitGroup
+
+	mu      sync.Mutex // protects below
+	loggers []*archiveLogger
+	closed  bool
+}
+
+// Logger implements log.Client.Logger().
+func (a *archiveClient) Logger(logID string) Logger {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	l := &archiveLogger{client: a, logID: logID, closed: a.closed}
+	a.loggers = append(a.loggers, l)
+	return l
+}
+
+// startWrite adds a background write to a.wg. It reports false if a has been
+// closed, in which case a.wg must not be added to.
+func (a *archiveClient) startWrite() bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return false
+	}
+	a.wg.Add(1)
+	return true
+}
+
+// Close implements log.Client.Close(). It writes any buffered entries. Entries
+// logged after Close are dropped.
+func (a *archiveClient) Close() error {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		return nil
+	}
+	a.closed = true
+	loggers := append([]*archiveLogger(nil), a.loggers...)
+	a.mu.Unlock()
+
+	close(a.done)
+	a.wg.Wait()
+
+	var es errs
+	for _, l := range loggers {
+		err := l.close()
+		if err != nil {
+			es = append(es, err)
+		}
+	}
+
+	if len(es) > 0 {
+		return fmt.Errorf("1 or more errors while closing archive client: %w", es)
+	}
+
+	return nil
+}
+
+// rollover periodically writes objects that are older than cfg.MaxObjectAge.
+func (a *archiveClient) rollover() {
+	defer a.wg.Done()
+
+	interval := a.cfg.MaxObjectAge / 4
+	if interval > time.Second*10 {
+		interval = time.Second * 10
+	}
+	t := time.NewTicker(interval)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-a.done:
+			return
+		case now := <-t.C:
+			a.mu.Lock()
+			loggers := append([]*archiveLogger(nil), a.loggers...)
+			a.mu.Unlock()
+
+			for _, l := range loggers {
+				l.flushIf(func(b *archiveBatch) bool { return now.Sub(b.started) >= a.cfg.MaxObjectAge })
+			}
+		}
+	}
+}
+
+type archiveLogger struct {
+	client *archiveClient
+	logID  string
+
+	mu     sync.Mutex // protects below
+	batch  *archiveBatch
+	closed bool // set by close(); entries are no longer appended to batch
+}
+
+type archiveBatch struct {
+	date    string
+	started time.Time
+	buf     bytes.Buffer
+}
+
+// StandardLogger implements log.Logger.StandardLogger().
+func (l *archiveLogger) StandardLogger(severity logging.Severity) *log.Logger {
+	return NewStandardLogger(l, severity)
+}
+
+// Log implements log.Logger.Log(). If the entry causes an object to be written,
+// then it is written in the background and errors are reported to Stderr.
+func (l *archiveLogger) Log(entry logging.Entry) {
+	b, err := l.append(entry)
+	if err != nil {
+		_, _ = fmt.Fprintf(Stderr, "gke: archive: %v\n", err)
+		return
+	}
+	if b == nil {
+		return
+	}
+
+	if !l.client.startWrite() {
+		// Closed after the entry was appended, so Close will not write b.
+		err := l.write(b)
+		if err != nil {
+			_, _ = fmt.Fprintf(Stderr, "gke: archive: %v\n", err)
+		}
+		return
+	}
+
+	go func() {
+		defer l.client.wg.Done()
+		err := l.write(b)
+		if err != nil {
+			_, _ = fmt.Fprintf(Stderr, "gke: archive: %v\n", err)
+		}
+	}()
+}
+
+// LogSync implements log.Logger.LogSync(). It returns ErrArchiveClosed if the
+// client has been closed.
+func (l *archiveLogger) LogSync(_ context.Context, entry logging.Entry) error {
+	b, err := l.append(entry)
+	if err != nil {
+		return err
+	}
+	if b != nil {
+		err := l.write(b)
+		if err != nil {
+			return err
+		}
+	}
+	return l.Flush()
+}
+
+// append adds entry to the current batch. If the batch is complete, then it is
+// returned so that it can be written. If the client has been closed, then the
+// entry is dropped and ErrArchiveClosed is returned. The check is made under
+// l.mu, so an entry is either in the batch written by close() or dropped.
+func (l *archiveLogger) append(entry logging.Entry) (full *archiveBatch, err error) {
+	SetupSourceLocation(&entry, 2)
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	line := marshalJSONEntry(l.logID, entry)
+
+	date := entry.Timestamp.UTC().Format("2006/01/02")
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil, fmt.Errorf("failed to archive entry for log %s: %w", l.logID, ErrArchiveClosed)
+	}
+
+	if l.batch != nil && l.batch.date != date {
+		full, l.batch = l.batch, nil
+	}
+	if l.batch == nil {
+		l.batch = &archiveBatch{date: date, started: time.Now()}
+	}
+
+	l.batch.buf.Write(line)
+	l.batch.buf.WriteByte('\n')
+
+	if full == nil && l.batch.buf.Len() >= l.client.cfg.MaxObjectBytes {
+		full, l.batch = l.batch, nil
+	}
+	return full, nil
+}
+
+// Flush implements log.Logger.Flush(). It writes the current batch.
+func (l *archiveLogger) Flush() error {
+	return l.flushIf(func(*archiveBatch) bool { return true })
+}
+
+// close writes the current batch and causes later entries to be dropped.
+func (l *archiveLogger) close() error {
+	l.mu.Lock()
+	l.closed = true
+	b := l.batch
+	l.batch = nil
+	l.mu.Unlock()
+
+	if b == nil {
+		return nil
+	}
+	return l.write(b)
+}
+
+func (l *archiveLogger) flushIf(cond func(b *archiveBatch) bool) error {
+	l.mu.Lock()
+	b := l.batch
+	if b == nil || !cond(b) {
+		l.mu.Unlock()
+		return nil
+	}
+	l.batch = nil
+	l.mu.Unlock()
+
+	return l.write(b)
+}
+
+// write writes b to a new object.
+func (l *archiveLogger) write(b *archiveBatch) error {
+	name := path.Join(l.client.cfg.Prefix, l.logID, b.date, b.started.UTC().Format("150405.000000000")) + ".ndjson"
+	if l.client.cfg.Gzip {
+		name += ".gz"
+	}
+
+	w := l.client.bucket.Object(name).NewWriter(l.client.ctx)
+	w.ContentType = "application/x-ndjson"
+
+	var err error
+	if l.client.cfg.Gzip {
+		w.ContentEncoding = "gzip"
+		zw := gzip.NewWriter(w)
+		_, err = zw.Write(b.buf.Bytes())
+		if err == nil {
+			err = zw.Close()
+		}
+	} else {
+		_, err = w.Write(b.buf.Bytes())
+	}
+
+	if err != nil {
+		_ = w.Close()
+		return fmt.Errorf("failed to write archive object %s: %w", name, err)
+	}
+
+	err = w.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write archive object %s: %w", name, err)
+	}
+
+	return nil
+}
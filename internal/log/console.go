@@ -0,0 +1,257 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"bytes"
+	"cloud.google.com/go/logging"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ConsoleClient writes human-friendly entries for local development. Each
+// entry is written as aligned columns followed by its structured payload and
+// labels pretty-printed as JSON.
+type ConsoleClient struct {
+	mu     *sync.Mutex
+	writer io.Writer
+	color  bool
+}
+
+// NewConsoleClient returns a new ConsoleClient that writes to writer. If color
+// is true, then severities are highlighted with ANSI escape codes.
+func NewConsoleClient(writer io.Writer, color bool) Client {
+	return ConsoleClient{&sync.Mutex{}, writer, color}
+}
+
+// IsTerminal reports whether w is a terminal that supports colors. It honors
+// the NO_COLOR environment variable.
+func IsTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok || os.Getenv("NO_COLOR") != "" || os.Getenv("TERM") == "dumb" {
+		return false
+	}
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+// Logger returns a logger with a provided logID.
+func (c ConsoleClient) Logger(logID string) Logger {
+	return consoleLogger{c, logID}
+}
+
+// Close implements log.Client.Close().
+// For ConsoleClient, it is a no-op.
+func (c ConsoleClient) Close() error {
+	return nil // no-op
+}
+
+type consoleLogger struct {
+	client ConsoleClient
+	logID  string
+}
+
+// StandardLogger implements log.Logger.StandardLogger().
+func (c consoleLogger) StandardLogger(severity logging.Severity) *log.Logger {
+	return NewStandardLogger(c, severity)
+}
+
+// Log implements log.Logger.Log().
+func (c consoleLogger) Log(entry logging.Entry) {
+	SetupSourceLocation(&entry, 1)
+	c.write(entry)
+}
+
+// LogSync implements log.Logger.LogSync().
+func (c consoleLogger) LogSync(_ context.Context, entry logging.Entry) error {
+	SetupSourceLocation(&entry, 1)
+	err := c.write(entry)
+	if err != nil {
+		return err
+	}
+	return c.Flush()
+}
+
+// Flush implements log.Logger.Flush().
+func (c consoleLogger) Flush() error {
+	if f, ok := c.client.writer.(flusher); ok {
+		_ = f.Flush()
+	}
+	if f, ok := c.client.writer.(syncer); ok {
+		_ = f.Sync()
+	}
+	return nil
+}
+
+var severityColors = map[logging.Severity]string{
+	logging.Debug:     "\x1b[90m",
+	logging.Info:      "\x1b[32m",
+	logging.Notice:    "\x1b[36m",
+	logging.Warning:   "\x1b[33m",
+	logging.Error:     "\x1b[31m",
+	logging.Critical:  "\x1b[1;31m",
+	logging.Alert:     "\x1b[1;31m",
+	logging.Emergency: "\x1b[1;41;97m",
+}
+
+const colorReset = "\x1b[0m"
+
+func (c consoleLogger) write(entry logging.Entry) error {
+	ts := entry.Timestamp
+	if ts.IsZero() {
+		ts = time.Now()
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString(ts.Format("15:04:05.000"))
+	buf.WriteByte(' ')
+
+	sev := fmt.Sprintf("%-9s", strings.ToUpper(entry.Severity.String()))
+	if color, ok := severityColors[entry.Severity]; ok && c.client.color {
+		sev = color + sev + colorReset
+	}
+	buf.WriteString(sev)
+
+	src := ""
+	if entry.SourceLocation != nil {
+		src = fmt.Sprintf("%s:%d", TrimSourcePath(entry.SourceLocation.File), entry.SourceLocation.Line)
+	}
+	_, _ = fmt.Fprintf(&buf, " %s %-24s ", c.logID, src)
+
+	msg, fields := consolePayload(entry.Payload)
+	buf.WriteString(msg)
+	buf.WriteByte('\n')
+
+	if fields != nil {
+		writeIndentedJSON(&buf, fields)
+	}
+
+	if len(entry.Labels) > 0 {
+		keys := make([]string, 0, len(entry.Labels))
+		for k := range entry.Labels {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buf.WriteString("    labels:")
+		for _, k := range keys {
+			_, _ = fmt.Fprintf(&buf, " %s=%q", k, entry.Labels[k])
+		}
+		buf.WriteByte('\n')
+	}
+
+	c.client.mu.Lock()
+	defer c.client.mu.Unlock()
+	_, err := c.client.writer.Write(buf.Bytes())
+	return err
+}
+
+// consolePayload splits payload into a message and any remaining structured fields.
+func consolePayload(payload interface{}) (msg string, fields interface{}) {
+	switch p := payload.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return p, nil
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Sprintf("%v", payload), nil
+	}
+
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return string(b), nil
+	}
+
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return "", v
+	}
+
+	if s, ok := m["message"].(string); ok {
+		delete(m, "message")
+		msg = s
+	}
+	if len(m) == 0 {
+		return msg, nil
+	}
+	return msg, m
+}
+
+func writeIndentedJSON(buf *bytes.Buffer, v interface{}) {
+	b, err := json.MarshalIndent(v, "    ", "  ")
+	if err != nil {
+		return
+	}
+	buf.WriteString("    ")
+	buf.Write(b)
+	buf.WriteByte('\n')
+}
+
+var (
+	pkgModuleRootOnce sync.Once // protects below
+	pkgModuleRoot     string
+)
+
+// TrimSourcePath shortens the path of a source file for display. Paths in the
+// module root (the nearest directory containing go.mod, starting at the working
+// directory) are made relative to it. If there is no go.mod, such as when a
+// binary is run outside of its source tree, then the working directory is used
+// instead. Paths in the module cache are trimmed to their module path. Other paths
+// are returned unchanged.
+func TrimSourcePath(file string) string {
+	pkgModuleRootOnce.Do(func() {
+		wd, err := os.Getwd()
+		if err == nil {
+			pkgModuleRoot = filepath.ToSlash(moduleRoot(wd)) + "/"
+		}
+	})
+
+	if pkgModuleRoot != "" && strings.HasPrefix(file, pkgModuleRoot) {
+		return file[len(pkgModuleRoot):]
+	}
+	if i := strings.LastIndex(file, "/pkg/mod/"); i >= 0 {
+		return file[i+len("/pkg/mod/"):]
+	}
+	return file
+}
+
+// moduleRoot returns the nearest directory containing go.mod, starting at dir
+// and walking up. If there is none, then dir is returned.
+func moduleRoot(dir string) string {
+	for d := dir; ; {
+		if _, err := os.Stat(filepath.Join(d, "go.mod")); err == nil {
+			return d
+		}
+		parent := filepath.Dir(d)
+		if parent == d {
+			return dir
+		}
+		d = parent
+	}
+}
@@ -0,0 +1,185 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"sync"
+	"time"
+)
+
+// NewDedupLogger returns a logger that collapses identical entries logged within
+// window. Entries are identical if their severity, call site and payload match.
+// Call sites are identified by source location (see SetupSourceLocation()), or by
+// program counter if source locations are not captured. The first entry is logged immediately.
+// If identical entries follow it within window, then a single entry reporting
+// how many times it was repeated, along with the first and last timestamps, is
+// logged at the end of window. Pending reports are also written by Flush().
+// While windows are open, the logger is added to pending, which may be nil.
+// Entries logged with LogSync() are never collapsed.
+func NewDedupLogger(l Logger, window time.Duration, pending *LoggerSet) Logger {
+	return &dedupLogger{Logger: l, window: window, pending: pending, sites: make(map[dedupKey]*dedupSite)}
+}
+
+type dedupLogger struct {
+	Logger
+	window  time.Duration
+	pending *LoggerSet // may be nil
+	mu      sync.Mutex // protects below
+	sites   map[dedupKey]*dedupSite
+}
+
+type dedupKey struct {
+	severity logging.Severity
+	site     siteKey
+	payload  uint64
+}
+
+type dedupSite struct {
+	entry    logging.Entry // last repeated entry
+	first    time.Time
+	last     time.Time
+	repeated int
+	timer    *time.Timer
+}
+
+// StandardLogger implements log.Logger.StandardLogger().
+func (d *dedupLogger) StandardLogger(severity logging.Severity) *log.Logger {
+	return NewStandardLogger(d, severity)
+}
+
+// Log implements log.Logger.Log().
+func (d *dedupLogger) Log(entry logging.Entry) {
+	SetupSourceLocation(&entry, 1)
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+
+	key := newDedupKey(entry)
+
+	d.mu.Lock()
+	site, found := d.sites[key]
+	if found {
+		site.entry, site.last = entry, entry.Timestamp
+		site.repeated++
+		d.mu.Unlock()
+		return
+	}
+
+	site = &dedupSite{first: entry.Timestamp, last: entry.Timestamp}
+	if len(d.sites) == 0 {
+		d.pending.Add(d)
+	}
+	d.sites[key] = site
+	site.timer = time.AfterFunc(d.window, func() { d.expire(key, site) })
+	d.mu.Unlock()
+
+	d.Logger.Log(entry)
+}
+
+func newDedupKey(entry logging.Entry) dedupKey {
+	result := dedupKey{severity: entry.Severity, site: newSiteKey(entry)}
+
+	h := fnv.New64a()
+	b, err := json.Marshal(entry.Payload)
+	if err != nil {
+		b = []byte(fmt.Sprintf("%#v", entry.Payload))
+	}
+	_, _ = h.Write(b)
+	result.payload = h.Sum64()
+
+	return result
+}
+
+// expire ends the window of site, logging a report if the entry was repeated.
+func (d *dedupLogger) expire(key dedupKey, site *dedupSite) {
+	d.mu.Lock()
+	if d.sites[key] != site {
+		d.mu.Unlock()
+		return // already flushed
+	}
+	delete(d.sites, key)
+	if len(d.sites) == 0 {
+		d.pending.Remove(d)
+	}
+	report := site.report()
+	d.mu.Unlock()
+
+	if report != nil {
+		d.Logger.Log(*report)
+	}
+}
+
+func (s *dedupSite) report() *logging.Entry {
+	if s.repeated == 0 {
+		return nil
+	}
+
+	result := s.entry
+	payload := map[string]interface{}{}
+	switch p := s.entry.Payload.(type) {
+	case string:
+		payload["message"] = fmt.Sprintf("%s (repeated %d times)", p, s.repeated)
+	case map[string]interface{}:
+		for k, v := range p {
+			payload[k] = v
+		}
+		payload["message"] = fmt.Sprintf("%v (repeated %d times)", p["message"], s.repeated)
+	default:
+		payload["message"] = fmt.Sprintf("repeated %d times", s.repeated)
+		payload["payload"] = p
+	}
+	payload["repeated"] = s.repeated
+	payload["firstTimestamp"] = s.first
+	payload["lastTimestamp"] = s.last
+	result.Payload = payload
+
+	return &result
+}
+
+// Flush implements log.Logger.Flush(). Pending reports of repeated entries are
+// logged before the underlying logger is flushed.
+func (d *dedupLogger) Flush() error {
+	d.mu.Lock()
+	var reports []*logging.Entry
+	for key, site := range d.sites {
+		site.timer.Stop()
+		delete(d.sites, key)
+		if r := site.report(); r != nil {
+			reports = append(reports, r)
+		}
+	}
+	d.pending.Remove(d)
+	d.mu.Unlock()
+
+	for _, r := range reports {
+		d.Logger.Log(*r)
+	}
+	return d.Logger.Flush()
+}
+
+// LogSync implements log.Logger.LogSync().
+func (d *dedupLogger) LogSync(ctx context.Context, entry logging.Entry) error {
+	SetupSourceLocation(&entry, 1)
+	return d.Logger.LogSync(ctx, entry)
+}
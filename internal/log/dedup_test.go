@@ -0,0 +1,54 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"cloud.google.com/go/logging"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDedupLogger_window(t *testing.T) {
+	var buf syncBuffer
+	lg := NewDedupLogger(NewStandardClient(&buf).Logger("test"), 100*time.Millisecond, nil)
+
+	for i := 0; i < 3; i++ {
+		lg.Log(logging.Entry{Severity: logging.Warning, Payload: "retrying"})
+	}
+	lg.Log(logging.Entry{Severity: logging.Error, Payload: "retrying"})
+
+	deadline := time.Now().Add(5 * time.Second)
+	for !strings.Contains(buf.String(), "retrying (repeated 2 times)") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected repeated report at the end of the window, got %q", buf.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := strings.Count(buf.String(), "\n"); got != 3 {
+		t.Fatalf("expected 3 entries, got %d: %q", got, buf.String())
+	}
+
+	lg.Log(logging.Entry{Severity: logging.Warning, Payload: "retrying"})
+	if err := lg.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(buf.String(), "\n"); got != 4 {
+		t.Errorf("expected entry after window to be logged, got %q", buf.String())
+	}
+}
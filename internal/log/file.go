@@ -0,0 +1,418 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"cloud.google.com/go/logging"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FileConfig configures a file client. See NewFileClient().
+type FileConfig struct {
+	// Path is the path of the log file.
+	Path string
+	// MaxBytes is the size at which the file is rotated. If 0, then the file
+	// is not rotated based on size.
+	MaxBytes int64
+	// MaxAge is the age at which the file is rotated. If 0, then the file is
+	// not rotated based on age.
+	MaxAge time.Duration
+	// MaxFiles is the number of rotated files that are retained. If 0, then
+	// all rotated files are retained.
+	MaxFiles int
+	// Gzip causes rotated files to be gzip compressed.
+	Gzip bool
+	// JSON causes entries to be written as newline-delimited JSON instead of
+	// the text format used by StandardClient.
+	JSON bool
+}
+
+// NewFileClient returns a client that writes entries to a rotating file. Rotated
+// files are renamed to PATH.YYYYMMDDTHHMMSS.NNNNNNNNN (with a .gz suffix if compressed).
+// The file is reopened when the process receives SIGHUP, so that it can be rotated
+// by an external tool. Close stops watching for SIGHUP and closes the file.
+func NewFileClient(cfg FileConfig) (Client, error) {
+	f, err := OpenRotatingFile(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &fileClient{file: f, json: cfg.JSON, hup: make(chan os.Signal, 1)}
+	if !cfg.JSON {
+		result.Client = NewStandardClient(f)
+	}
+
+	signal.Notify(result.hup, syscall.SIGHUP)
+	go func() {
+		for range result.hup {
+			err := f.Reopen()
+			if err != nil {
+				_, _ = fmt.Fprintf(Stderr, "gke: failed to reopen log file: %v\n", err)
+			}
+		}
+	}()
+
+	return result, nil
+}
+
+type fileClient struct {
+	Client
+	file *RotatingFile
+	json bool
+	hup  chan os.Signal
+
+	closeOnce sync.Once
+	closeErr  error
+}
+
+// Logger implements log.Client.Logger().
+func (c *fileClient) Logger(logID string) Logger {
+	if c.json {
+		return jsonLogger{c.file, logID}
+	}
+	return c.Client.Logger(logID)
+}
+
+// Close implements log.Client.Close(). Calling Close more than once returns
+// the result of the first call.
+func (c *fileClient) Close() error {
+	c.closeOnce.Do(func() {
+		signal.Stop(c.hup)
+		close(c.hup)
+		c.closeErr = c.file.Close()
+	})
+	return c.closeErr
+}
+
+// jsonLogger writes entries to a writer as newline-delimited JSON.
+type jsonLogger struct {
+	writer io.Writer
+	logID  string
+}
+
+// StandardLogger implements log.Logger.StandardLogger().
+func (j jsonLogger) StandardLogger(severity logging.Severity) *log.Logger {
+	return NewStandardLogger(j, severity)
+}
+
+// Log implements log.Logger.Log().
+func (j jsonLogger) Log(entry logging.Entry) {
+	SetupSourceLocation(&entry, 1)
+	_ = j.write(entry)
+}
+
+// LogSync implements log.Logger.LogSync().
+func (j jsonLogger) LogSync(_ context.Context, entry logging.Entry) error {
+	SetupSourceLocation(&entry, 1)
+	err := j.write(entry)
+	if err != nil {
+		return err
+	}
+	return j.Flush()
+}
+
+func (j jsonLogger) write(entry logging.Entry) error {
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	line := marshalJSONEntry(j.logID, entry)
+	_, err := j.writer.Write(append(line, '\n'))
+	return err
+}
+
+// Flush implements log.Logger.Flush().
+func (j jsonLogger) Flush() error {
+	if f, ok := j.writer.(flusher); ok {
+		_ = f.Flush()
+	}
+	if f, ok := j.writer.(syncer); ok {
+		_ = f.Sync()
+	}
+	return nil
+}
+
+// RotatingFile is an io.Writer that writes to a file that is rotated based on
+// its size and age. It is safe for concurrent use.
+type RotatingFile struct {
+	cfg     FileConfig
+	wg      sync.WaitGroup // tracks compression and pruning of rotated files
+	pruneMu sync.Mutex     // serializes compression and pruning of rotated files
+
+	mu     sync.Mutex // protects below
+	file   *os.File   // nil if it could not be reopened; see reopen()
+	closed bool
+	size   int64
+	opened time.Time
+}
+
+// OpenRotatingFile opens (or creates) the file at cfg.Path for appending.
+func OpenRotatingFile(cfg FileConfig) (*RotatingFile, error) {
+	result := &RotatingFile{cfg: cfg}
+	err := result.open()
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.cfg.Path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	fi, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+
+	r.file, r.size, r.opened = f, fi.Size(), time.Now()
+	return nil
+}
+
+// reopen opens the file at cfg.Path if it is not open. The file is left unopened
+// when a rotation or Reopen() fails to open it, and it is retried by the next
+// call. It returns os.ErrClosed if r has been closed. r.mu must be held.
+func (r *RotatingFile) reopen() error {
+	if r.closed {
+		return os.ErrClosed
+	}
+	if r.file != nil {
+		return nil
+	}
+	return r.open()
+}
+
+// Write implements io.Writer. The file is rotated before p is written if
+// writing p would exceed cfg.MaxBytes or the file is older than cfg.MaxAge.
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.reopen()
+	if err != nil {
+		return 0, err
+	}
+
+	if r.size > 0 && (r.cfg.MaxBytes > 0 && r.size+int64(len(p)) > r.cfg.MaxBytes ||
+		r.cfg.MaxAge > 0 && time.Since(r.opened) >= r.cfg.MaxAge) {
+		err := r.rotate()
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Rotate renames the current file and opens a new one. If the file is not open
+// because an earlier rotation failed to open the new file, then it is opened.
+func (r *RotatingFile) Rotate() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return os.ErrClosed
+	}
+	if r.file == nil {
+		return r.open()
+	}
+	return r.rotate()
+}
+
+func (r *RotatingFile) rotate() error {
+	err := r.file.Close()
+	if err != nil {
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	rotated := r.cfg.Path + "." + time.Now().UTC().Format("20060102T150405.000000000")
+	err = os.Rename(r.cfg.Path, rotated)
+	if err != nil {
+		// Keep writing to the original file rather than leaving r closed.
+		if oerr := r.open(); oerr != nil {
+			r.file = nil
+			return fmt.Errorf("failed to rotate log file: %w", errs{err, oerr})
+		}
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		err := r.compressAndPrune(rotated)
+		if err != nil {
+			_, _ = fmt.Fprintf(Stderr, "gke: %v\n", err)
+		}
+	}()
+
+	err = r.open()
+	if err != nil {
+		r.file = nil // retried by the next write
+		return err
+	}
+
+	return nil
+}
+
+// compressAndPrune compresses the rotated file if configured, then removes the
+// oldest rotated files in excess of cfg.MaxFiles.
+func (r *RotatingFile) compressAndPrune(rotated string) error {
+	r.pruneMu.Lock()
+	defer r.pruneMu.Unlock()
+
+	if r.cfg.Gzip {
+		err := gzipFile(rotated)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to compress rotated log file: %w", err)
+		}
+	}
+
+	if r.cfg.MaxFiles <= 0 {
+		return nil
+	}
+
+	matches, err := filepath.Glob(r.cfg.Path + ".*")
+	if err != nil {
+		return fmt.Errorf("failed to prune rotated log files: %w", err)
+	}
+
+	var files []string
+	for _, m := range matches {
+		if !strings.HasSuffix(m, ".tmp") {
+			files = append(files, m)
+		}
+	}
+	sort.Strings(files)
+
+	var es errs
+	for len(files) > r.cfg.MaxFiles {
+		err := os.Remove(files[0])
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			es = append(es, err)
+		}
+		files = files[1:]
+	}
+
+	if len(es) > 0 {
+		return fmt.Errorf("failed to prune rotated log files: %w", es)
+	}
+
+	return nil
+}
+
+func gzipFile(name string) error {
+	in, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := os.Create(name + ".gz.tmp")
+	if err != nil {
+		return err
+	}
+
+	zw := gzip.NewWriter(out)
+	_, err = io.Copy(zw, in)
+	if err == nil {
+		err = zw.Close()
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(out.Name())
+		return err
+	}
+
+	err = os.Rename(out.Name(), name+".gz")
+	if err != nil {
+		return err
+	}
+	return os.Remove(name)
+}
+
+// Reopen closes and reopens the file at cfg.Path. It is used after the file
+// has been moved by an external tool (e.g. logrotate).
+func (r *RotatingFile) Reopen() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.closed {
+		return os.ErrClosed
+	}
+
+	if r.file != nil {
+		_ = r.file.Close()
+	}
+	err := r.open()
+	if err != nil {
+		r.file = nil // retried by the next write
+	}
+	return err
+}
+
+// Flush implements the flusher interface checked by standardLogger.Flush().
+// Writes are not buffered, so it is a no-op.
+func (r *RotatingFile) Flush() error {
+	return nil // no-op
+}
+
+// Sync commits the contents of the file to stable storage.
+func (r *RotatingFile) Sync() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	err := r.reopen()
+	if err != nil {
+		return err
+	}
+	return r.file.Sync()
+}
+
+// Close closes the file and waits for rotated files to be compressed and pruned.
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	var err error
+	if r.file != nil {
+		err = r.file.Close()
+		r.file = nil
+	}
+	r.closed = true
+	r.mu.Unlock()
+
+	r.wg.Wait()
+	return err
+}
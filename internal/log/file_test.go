@@ -0,0 +1,150 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"cloud.google.com/go/logging"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotatingFile_renameFails(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	f, err := OpenRotatingFile(FileConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// Removing the file makes the rename fail.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Rotate(); err == nil {
+		t.Fatal("expected rotate to fail")
+	}
+
+	if _, err := f.Write([]byte("still logging\n")); err != nil {
+		t.Fatalf("expected writes to continue after a failed rotation, got %v", err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "still logging\n" {
+		t.Errorf("expected the original path to be reopened, got %q", b)
+	}
+}
+
+func TestRotatingFile_openFails(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "app.log")
+	f, err := OpenRotatingFile(FileConfig{Path: path})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	// Removing the file and making the directory unwritable makes both the
+	// rename and reopening the original path fail.
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(dir, 0555); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(dir, 0755)
+	if probe, err := os.Create(filepath.Join(dir, "probe")); err == nil {
+		_ = probe.Close()
+		t.Skip("directory permissions are not enforced for this user")
+	}
+
+	if err := f.Rotate(); err == nil {
+		t.Fatal("expected rotate to fail")
+	}
+	if _, err := f.Write([]byte("lost\n")); err == nil || errors.Is(err, os.ErrClosed) {
+		t.Fatalf("expected the open error while the directory is unwritable, got %v", err)
+	}
+
+	if err := os.Chmod(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("recovered\n")); err != nil {
+		t.Fatalf("expected the file to be reopened by the next write, got %v", err)
+	}
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "recovered\n" {
+		t.Errorf("expected the file to be reopened, got %q", b)
+	}
+
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Write([]byte("closed\n")); !errors.Is(err, os.ErrClosed) {
+		t.Errorf("expected os.ErrClosed after Close, got %v", err)
+	}
+}
+
+func TestFileClient_Close(t *testing.T) {
+	c, err := NewFileClient(FileConfig{Path: filepath.Join(t.TempDir(), "app.log")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.Close(); err != nil {
+		t.Errorf("expected a second Close to return the first result, got %v", err)
+	}
+}
+
+func TestFileClient_httpRequest(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "app.log")
+	c, err := NewFileClient(FileConfig{Path: path, JSON: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := httptest.NewRequest("GET", "/items?id=1", nil)
+	r.Header.Set("User-Agent", "test")
+	c.Logger("access").Log(logging.Entry{
+		Payload:     "GET /items?id=1 200",
+		HTTPRequest: &logging.HTTPRequest{Request: r, Status: 200, ResponseSize: 5, Latency: 1500 * time.Millisecond, RemoteIP: "203.0.113.7"},
+	})
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `"httpRequest":{"requestMethod":"GET","requestUrl":"/items?id=1","status":200,"responseSize":"5","userAgent":"test","remoteIp":"203.0.113.7","latency":"1.5s","protocol":"HTTP/1.1"}`
+	if !strings.Contains(string(b), want) {
+		t.Errorf("expected %s in %s", want, b)
+	}
+}
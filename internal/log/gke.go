@@ -21,6 +21,7 @@ import (
 	"cloud.google.com/go/logging"
 	"context"
 	"fmt"
+	"google.golang.org/api/option"
 	mrpb "google.golang.org/genproto/googleapis/api/monitoredres"
 	"strings"
 
@@ -37,36 +38,92 @@ import (
 // as a project ID.
 //
 // Note: NewGkeClient uses WriteScope.
-func NewGkeClient(ctx context.Context, parent string) (GkeClient, error) {
-	client, err := logging.NewClient(ctx, parent)
+func NewGkeClient(ctx context.Context, parent string, opts ...GkeClientOption) (GkeClient, error) {
+	var cfg gkeClientConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	client, err := logging.NewClient(ctx, parent, cfg.clientOpts...)
 	if err != nil {
 		return GkeClient{}, err
 	}
-	return GkeClient{client}, nil
+	if cfg.onError != nil {
+		client.OnError = cfg.onError
+	}
+	return GkeClient{client, cfg.loggerOpts, cfg.md}, nil
+}
+
+// GkeClientOption configures a GkeClient.
+type GkeClientOption func(*gkeClientConfig)
+
+type gkeClientConfig struct {
+	loggerOpts []logging.LoggerOption
+	clientOpts []option.ClientOption
+	onError    func(error)
+	md         *metadata.MetadataType
+}
+
+// WithLoggerOptions adds options that are passed to each logger provisioned
+// by the client, such as logging.DelayThreshold().
+func WithLoggerOptions(opts ...logging.LoggerOption) GkeClientOption {
+	return func(c *gkeClientConfig) {
+		c.loggerOpts = append(c.loggerOpts, opts...)
+	}
+}
+
+// WithOnError sets the function that is called when an error occurs while
+// writing entries asynchronously. See logging.Client.OnError.
+func WithOnError(f func(error)) GkeClientOption {
+	return func(c *gkeClientConfig) {
+		c.onError = f
+	}
+}
+
+// WithClientOptions adds options that are passed to logging.NewClient(), such
+// as option.WithEndpoint().
+func WithClientOptions(opts ...option.ClientOption) GkeClientOption {
+	return func(c *gkeClientConfig) {
+		c.clientOpts = append(c.clientOpts, opts...)
+	}
+}
+
+// WithMetadata sets the metadata used to populate the monitored resource and
+// labels of each logger. By default, metadata.Metadata() is used.
+func WithMetadata(md *metadata.MetadataType) GkeClientOption {
+	return func(c *gkeClientConfig) {
+		c.md = md
+	}
 }
 
 // GkeClient is a Logging client. A Client is associated with a single Cloud project.
 type GkeClient struct {
-	client *logging.Client
+	client     *logging.Client
+	loggerOpts []logging.LoggerOption
+	md         *metadata.MetadataType
 }
 
 // Logger returns a Logger that will write entries with the given log ID, such as
 // "syslog". A log ID must be less than 512 characters long and can only
 // include the following characters: upper and lower case alphanumeric
 // characters: [A-Za-z0-9]; and punctuation characters: forward-slash,
-// underscore, hyphen, and period.
+// underscore, hyphen, and period. Payloads are normalized with NormalizePayload()
+// before they are queued, so that they cannot fail to be written.
 func (g GkeClient) Logger(logID string) Logger {
-	md, err := metadata.Metadata()
-	if err != nil {
-		panic(fmt.Errorf("failed to create GkeClient: %w", err))
+	md := g.md
+	if md == nil {
+		var err error
+		md, err = metadata.Metadata()
+		if err != nil {
+			panic(fmt.Errorf("failed to create GkeClient: %w", err))
+		}
 	}
 	labels := make(map[string]string, len(md.PodLabels))
 	for k, v := range md.PodLabels {
 		k = "k8s-pod/" + strings.ReplaceAll(k, ".", "_")
 		labels[k] = v
 	}
-	return g.client.Logger(
-		logID,
+	opts := []logging.LoggerOption{
 		logging.CommonResource(&mrpb.MonitoredResource{
 			Type: "k8s_container",
 			Labels: map[string]string{
@@ -79,7 +136,8 @@ func (g GkeClient) Logger(logID string) Logger {
 			},
 		}),
 		logging.CommonLabels(labels),
-	)
+	}
+	return NewNormalizingLogger(g.client.Logger(logID, append(opts, g.loggerOpts...)...))
 }
 
 // Close waits for all opened loggers to be flushed and closes the client.
@@ -0,0 +1,136 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"cloud.google.com/go/logging"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// jsonRecord is the JSON representation of an entry written by the archive and
+// file clients. It mirrors the field names of the Cloud Logging LogEntry.
+type jsonRecord struct {
+	LogName        string            `json:"logName"`
+	Timestamp      time.Time         `json:"timestamp"`
+	Severity       string            `json:"severity"`
+	InsertID       string            `json:"insertId,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	Trace          string            `json:"trace,omitempty"`
+	SpanID         string            `json:"spanId,omitempty"`
+	TraceSampled   bool              `json:"traceSampled,omitempty"`
+	SourceLocation interface{}       `json:"sourceLocation,omitempty"`
+	Operation      interface{}       `json:"operation,omitempty"`
+	HTTPRequest    *jsonHTTPRequest  `json:"httpRequest,omitempty"`
+	TextPayload    string            `json:"textPayload,omitempty"`
+	JSONPayload    json.RawMessage   `json:"jsonPayload,omitempty"`
+}
+
+// jsonHTTPRequest is the JSON representation of a logging.HTTPRequest. It mirrors
+// the field names and formats of the Cloud Logging HttpRequest.
+type jsonHTTPRequest struct {
+	RequestMethod                  string `json:"requestMethod,omitempty"`
+	RequestURL                     string `json:"requestUrl,omitempty"`
+	RequestSize                    int64  `json:"requestSize,omitempty,string"`
+	Status                         int    `json:"status,omitempty"`
+	ResponseSize                   int64  `json:"responseSize,omitempty,string"`
+	UserAgent                      string `json:"userAgent,omitempty"`
+	RemoteIP                       string `json:"remoteIp,omitempty"`
+	ServerIP                       string `json:"serverIp,omitempty"`
+	Referer                        string `json:"referer,omitempty"`
+	Latency                        string `json:"latency,omitempty"`
+	CacheLookup                    bool   `json:"cacheLookup,omitempty"`
+	CacheHit                       bool   `json:"cacheHit,omitempty"`
+	CacheValidatedWithOriginServer bool   `json:"cacheValidatedWithOriginServer,omitempty"`
+	CacheFillBytes                 int64  `json:"cacheFillBytes,omitempty,string"`
+	Protocol                       string `json:"protocol,omitempty"`
+}
+
+func newJSONHTTPRequest(r *logging.HTTPRequest) *jsonHTTPRequest {
+	result := &jsonHTTPRequest{
+		RequestSize:                    r.RequestSize,
+		Status:                         r.Status,
+		ResponseSize:                   r.ResponseSize,
+		RemoteIP:                       r.RemoteIP,
+		ServerIP:                       r.LocalIP,
+		CacheLookup:                    r.CacheLookup,
+		CacheHit:                       r.CacheHit,
+		CacheValidatedWithOriginServer: r.CacheValidatedWithOriginServer,
+		CacheFillBytes:                 r.CacheFillBytes,
+	}
+	if r.Latency > 0 {
+		result.Latency = strconv.FormatFloat(r.Latency.Seconds(), 'f', -1, 64) + "s"
+	}
+	if req := r.Request; req != nil {
+		result.RequestMethod = req.Method
+		result.UserAgent = req.UserAgent()
+		result.Referer = req.Referer()
+		result.Protocol = req.Proto
+		if req.URL != nil {
+			result.RequestURL = req.URL.String()
+		}
+	}
+	return result
+}
+
+func newJSONRecord(logID string, entry logging.Entry) jsonRecord {
+	result := jsonRecord{
+		LogName:      logID,
+		Timestamp:    entry.Timestamp,
+		Severity:     entry.Severity.String(),
+		InsertID:     entry.InsertID,
+		Labels:       entry.Labels,
+		Trace:        entry.Trace,
+		SpanID:       entry.SpanID,
+		TraceSampled: entry.TraceSampled,
+	}
+	if entry.SourceLocation != nil {
+		result.SourceLocation = entry.SourceLocation
+	}
+	if entry.Operation != nil {
+		result.Operation = entry.Operation
+	}
+	if entry.HTTPRequest != nil {
+		result.HTTPRequest = newJSONHTTPRequest(entry.HTTPRequest)
+	}
+
+	switch p := NormalizePayload(entry.Payload).(type) {
+	case string:
+		result.TextPayload = p
+	case json.RawMessage:
+		result.JSONPayload = p
+	}
+
+	return result
+}
+
+// marshalJSONEntry returns the JSON representation of entry. If entry cannot be
+// serialized, then a description of the failure is returned in its place.
+func marshalJSONEntry(logID string, entry logging.Entry) []byte {
+	line, err := json.Marshal(newJSONRecord(logID, entry))
+	if err != nil {
+		line, _ = json.Marshal(newJSONRecord(logID, logging.Entry{
+			Timestamp: entry.Timestamp,
+			Severity:  entry.Severity,
+			Payload:   fmt.Sprintf("failed to serialize entry: %v", err),
+		}))
+	}
+	return line
+}
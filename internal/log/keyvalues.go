@@ -0,0 +1,79 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"fmt"
+	"time"
+)
+
+// BadKey is the key used for values in a key-value list that do not
+// have a valid string key.
+const BadKey = "!BADKEY"
+
+// MessageKey is the key of the message in a key-value payload. It is reserved,
+// so values for it in a key-value list are stored in the BadKey field instead.
+const MessageKey = "message"
+
+// AddKeyValues stores each key-value pair in m. keysAndValues must alternate
+// between string keys and values. Values without a string key, including a trailing
+// value, and values for the reserved MessageKey are stored in the BadKey field. Errors are converted to their message and
+// time.Duration values are converted to strings (e.g. "1.5s") to keep them readable.
+func AddKeyValues(m map[string]interface{}, keysAndValues ...interface{}) {
+	var bad []interface{}
+	for i := 0; i < len(keysAndValues); i++ {
+		key, ok := keysAndValues[i].(string)
+		if !ok || i+1 == len(keysAndValues) {
+			bad = append(bad, keyValue(keysAndValues[i]))
+			continue
+		}
+		i++
+		if key == MessageKey {
+			bad = append(bad, keyValue(keysAndValues[i]))
+			continue
+		}
+		m[key] = keyValue(keysAndValues[i])
+	}
+
+	if prev, ok := m[BadKey]; ok && len(bad) > 0 {
+		if vs, ok := prev.([]interface{}); ok {
+			bad = append(vs[:len(vs):len(vs)], bad...)
+		} else {
+			bad = append([]interface{}{prev}, bad...)
+		}
+	}
+
+	switch len(bad) {
+	case 0:
+	case 1:
+		m[BadKey] = bad[0]
+	default:
+		m[BadKey] = bad
+	}
+}
+
+func keyValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case error:
+		return fmt.Sprintf("%v", v)
+	case time.Duration:
+		return v.String()
+	default:
+		return v
+	}
+}
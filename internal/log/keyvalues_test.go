@@ -0,0 +1,34 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+package log
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAddKeyValues_badKey(t *testing.T) {
+	m := map[string]interface{}{}
+	AddKeyValues(m, "first")
+	AddKeyValues(m, MessageKey, "second", "third")
+	AddKeyValues(m, "fourth")
+
+	want := []interface{}{"first", "second", "third", "fourth"}
+	if got := m[BadKey]; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected %v, got %#v", want, got)
+	}
+}
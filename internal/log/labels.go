@@ -0,0 +1,65 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"log"
+)
+
+// NewLabelsLogger returns a Logger that adds the provided labels to each entry
+// before passing it on to l. Labels that are already set on an entry are left unchanged.
+func NewLabelsLogger(l Logger, labels map[string]string) Logger {
+	return labelsLogger{l, labels}
+}
+
+type labelsLogger struct {
+	Logger
+	labels map[string]string
+}
+
+func (l labelsLogger) setupLabels(entry *logging.Entry) {
+	result := make(map[string]string, len(l.labels)+len(entry.Labels))
+	for k, v := range l.labels {
+		result[k] = v
+	}
+	for k, v := range entry.Labels {
+		result[k] = v
+	}
+	entry.Labels = result
+}
+
+// StandardLogger implements log.Logger.StandardLogger().
+func (l labelsLogger) StandardLogger(severity logging.Severity) *log.Logger {
+	return NewStandardLogger(l, severity)
+}
+
+// Log implements log.Logger.Log().
+func (l labelsLogger) Log(entry logging.Entry) {
+	SetupSourceLocation(&entry, 1)
+	l.setupLabels(&entry)
+	l.Logger.Log(entry)
+}
+
+// LogSync implements log.Logger.LogSync().
+func (l labelsLogger) LogSync(ctx context.Context, entry logging.Entry) error {
+	SetupSourceLocation(&entry, 1)
+	l.setupLabels(&entry)
+	return l.Logger.LogSync(ctx, entry)
+}
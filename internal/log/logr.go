@@ -0,0 +1,104 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"cloud.google.com/go/logging"
+	"github.com/go-logr/logr"
+)
+
+// NewLogrSink returns a logr.LogSink that writes to l. Messages with a V-level of
+// 0 are logged with Info severity, messages with a higher V-level are logged with
+// Debug severity and errors are logged with Error severity. Messages with a V-level
+// greater than verbosity are discarded.
+func NewLogrSink(l Logger, verbosity int) logr.LogSink {
+	return &logrSink{logger: l, verbosity: verbosity}
+}
+
+type logrSink struct {
+	logger    Logger
+	verbosity int
+	name      string
+	values    []interface{}
+	callDepth int
+}
+
+// Init implements logr.LogSink.Init().
+func (s *logrSink) Init(info logr.RuntimeInfo) {
+	s.callDepth += info.CallDepth
+}
+
+// Enabled implements logr.LogSink.Enabled().
+func (s *logrSink) Enabled(level int) bool {
+	return level <= s.verbosity
+}
+
+// Info implements logr.LogSink.Info().
+func (s *logrSink) Info(level int, msg string, keysAndValues ...interface{}) {
+	severity := logging.Info
+	if level > 0 {
+		severity = logging.Debug
+	}
+	s.log(severity, nil, msg, keysAndValues)
+}
+
+// Error implements logr.LogSink.Error().
+func (s *logrSink) Error(err error, msg string, keysAndValues ...interface{}) {
+	s.log(logging.Error, err, msg, keysAndValues)
+}
+
+func (s *logrSink) log(severity logging.Severity, err error, msg string, keysAndValues []interface{}) {
+	payload := make(map[string]interface{}, 2+(len(s.values)+len(keysAndValues))/2)
+	payload[MessageKey] = msg
+	if s.name != "" {
+		payload["logger"] = s.name
+	}
+	if err != nil {
+		payload["error"] = err.Error()
+	}
+	AddKeyValues(payload, s.values...)
+	AddKeyValues(payload, keysAndValues...)
+
+	entry := logging.Entry{Severity: severity, Payload: payload}
+	SetupSourceLocation(&entry, 2+s.callDepth)
+	s.logger.Log(entry)
+}
+
+// WithValues implements logr.LogSink.WithValues().
+func (s *logrSink) WithValues(keysAndValues ...interface{}) logr.LogSink {
+	result := *s
+	result.values = append(s.values[:len(s.values):len(s.values)], keysAndValues...)
+	return &result
+}
+
+// WithName implements logr.LogSink.WithName().
+func (s *logrSink) WithName(name string) logr.LogSink {
+	result := *s
+	if s.name != "" {
+		name = s.name + "/" + name
+	}
+	result.name = name
+	return &result
+}
+
+// WithCallDepth implements logr.CallDepthLogSink.WithCallDepth().
+func (s *logrSink) WithCallDepth(depth int) logr.LogSink {
+	result := *s
+	result.callDepth += depth
+	return &result
+}
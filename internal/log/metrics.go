@@ -0,0 +1,252 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"bufio"
+	"cloud.google.com/go/logging"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DropReason describes why an entry was not written.
+type DropReason string
+
+const (
+	// DropFiltered is used for entries below the minimum severity of a client.
+	DropFiltered DropReason = "filter"
+	// DropSampled is used for entries suppressed by a sampling logger.
+	DropSampled DropReason = "sampler"
+	// DropOverflow is used for entries discarded because a buffer overflowed.
+	DropOverflow DropReason = "overflow"
+)
+
+// DefaultMetrics collects metrics for the loggers in this process.
+var DefaultMetrics = NewMetrics()
+
+// Metrics counts logged and dropped entries, and write errors. It is safe
+// for concurrent use. Methods on a nil *Metrics are no-ops.
+type Metrics struct {
+	mu          sync.Mutex // protects below
+	logged      map[loggedKey]int64
+	dropped     map[droppedKey]int64
+	writeErrors int64
+}
+
+type loggedKey struct {
+	logID    string
+	severity logging.Severity
+}
+
+type droppedKey struct {
+	logID  string
+	reason DropReason
+}
+
+// NewMetrics returns an empty Metrics.
+func NewMetrics() *Metrics {
+	return &Metrics{logged: make(map[loggedKey]int64), dropped: make(map[droppedKey]int64)}
+}
+
+// AddLogged counts an entry logged to logID.
+func (m *Metrics) AddLogged(logID string, severity logging.Severity) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.logged[loggedKey{logID, severity}]++
+}
+
+// AddDropped counts an entry for logID that was dropped. logID is empty if unknown.
+// It returns the number of entries dropped for logID and reason so far.
+func (m *Metrics) AddDropped(logID string, reason DropReason) int64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := droppedKey{logID, reason}
+	m.dropped[key]++
+	return m.dropped[key]
+}
+
+// Dropped returns the number of entries dropped for logID and reason.
+func (m *Metrics) Dropped(logID string, reason DropReason) int64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dropped[droppedKey{logID, reason}]
+}
+
+// AddWriteError counts a write error. It returns the number of write errors so far.
+func (m *Metrics) AddWriteError() int64 {
+	if m == nil {
+		return 0
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.writeErrors++
+	return m.writeErrors
+}
+
+// LoggedCount is the number of entries logged to a log with a severity.
+type LoggedCount struct {
+	LogID    string
+	Severity logging.Severity
+	Count    int64
+}
+
+// DroppedCount is the number of entries for a log that were dropped for a reason.
+type DroppedCount struct {
+	LogID  string
+	Reason DropReason
+	Count  int64
+}
+
+// MetricsSnapshot is a point in time copy of Metrics.
+type MetricsSnapshot struct {
+	// Logged is sorted by log ID, then severity.
+	Logged []LoggedCount
+	// Dropped is sorted by log ID, then reason.
+	Dropped []DroppedCount
+	// WriteErrors is the number of errors returned when writing entries.
+	WriteErrors int64
+}
+
+// Snapshot returns a copy of the current metrics.
+func (m *Metrics) Snapshot() MetricsSnapshot {
+	var result MetricsSnapshot
+	if m == nil {
+		return result
+	}
+
+	m.mu.Lock()
+	for k, v := range m.logged {
+		result.Logged = append(result.Logged, LoggedCount{k.logID, k.severity, v})
+	}
+	for k, v := range m.dropped {
+		result.Dropped = append(result.Dropped, DroppedCount{k.logID, k.reason, v})
+	}
+	result.WriteErrors = m.writeErrors
+	m.mu.Unlock()
+
+	sort.Slice(result.Logged, func(i, j int) bool {
+		a, b := result.Logged[i], result.Logged[j]
+		return a.LogID < b.LogID || a.LogID == b.LogID && a.Severity < b.Severity
+	})
+	sort.Slice(result.Dropped, func(i, j int) bool {
+		a, b := result.Dropped[i], result.Dropped[j]
+		return a.LogID < b.LogID || a.LogID == b.LogID && a.Reason < b.Reason
+	})
+
+	return result
+}
+
+// WritePrometheus writes the metrics to w in the Prometheus text exposition format.
+func (s MetricsSnapshot) WritePrometheus(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	_, _ = fmt.Fprintln(bw, "# HELP gke_log_entries_total Number of log entries passed on to be written by log ID and severity, excluding dropped entries.")
+	_, _ = fmt.Fprintln(bw, "# TYPE gke_log_entries_total counter")
+	for _, c := range s.Logged {
+		_, _ = fmt.Fprintf(bw, "gke_log_entries_total{log_id=%s,severity=%s} %d\n", promLabel(c.LogID), promLabel(strings.ToUpper(c.Severity.String())), c.Count)
+	}
+
+	_, _ = fmt.Fprintln(bw, "# HELP gke_log_entries_dropped_total Number of log entries dropped by log ID and reason.")
+	_, _ = fmt.Fprintln(bw, "# TYPE gke_log_entries_dropped_total counter")
+	for _, c := range s.Dropped {
+		_, _ = fmt.Fprintf(bw, "gke_log_entries_dropped_total{log_id=%s,reason=%s} %d\n", promLabel(c.LogID), promLabel(string(c.Reason)), c.Count)
+	}
+
+	_, _ = fmt.Fprintln(bw, "# HELP gke_log_write_errors_total Number of errors while writing log entries.")
+	_, _ = fmt.Fprintln(bw, "# TYPE gke_log_write_errors_total counter")
+	_, _ = fmt.Fprintf(bw, "gke_log_write_errors_total %d\n", s.WriteErrors)
+
+	return bw.Flush()
+}
+
+// promLabel quotes v as a Prometheus label value.
+func promLabel(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
+
+// ServeHTTP implements http.Handler. It writes the metrics in the Prometheus text exposition format.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	_ = m.Snapshot().WritePrometheus(w)
+}
+
+// NewMetricsClient returns a client whose loggers are wrapped with NewMetricsLogger().
+// Wrapping the client before filtering or sampling it (see NewFilterClient() and
+// NewSamplingClient()) causes only the entries that pass through to be counted.
+func NewMetricsClient(c Client, m *Metrics) Client {
+	return metricsClient{c, m}
+}
+
+type metricsClient struct {
+	Client
+	metrics *Metrics
+}
+
+// Logger implements log.Client.Logger().
+func (c metricsClient) Logger(logID string) Logger {
+	return NewMetricsLogger(c.Client.Logger(logID), logID, c.metrics)
+}
+
+// NewMetricsLogger returns a logger that counts entries logged to logID in m.
+func NewMetricsLogger(l Logger, logID string, m *Metrics) Logger {
+	return metricsLogger{l, logID, m}
+}
+
+type metricsLogger struct {
+	Logger
+	logID   string
+	metrics *Metrics
+}
+
+// StandardLogger implements log.Logger.StandardLogger().
+func (m metricsLogger) StandardLogger(severity logging.Severity) *log.Logger {
+	return NewStandardLogger(m, severity)
+}
+
+// Log implements log.Logger.Log().
+func (m metricsLogger) Log(entry logging.Entry) {
+	SetupSourceLocation(&entry, 1)
+	m.metrics.AddLogged(m.logID, entry.Severity)
+	m.Logger.Log(entry)
+}
+
+// LogSync implements log.Logger.LogSync().
+func (m metricsLogger) LogSync(ctx context.Context, entry logging.Entry) error {
+	SetupSourceLocation(&entry, 1)
+	m.metrics.AddLogged(m.logID, entry.Severity)
+	err := m.Logger.LogSync(ctx, entry)
+	if err != nil {
+		m.metrics.AddWriteError()
+	}
+	return err
+}
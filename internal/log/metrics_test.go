@@ -0,0 +1,105 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"bytes"
+	"cloud.google.com/go/logging"
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMetricsLogger(t *testing.T) {
+	m := NewMetrics()
+	lg := NewMetricsLogger(NewStandardClient(ioutil.Discard).Logger("app"), "app", m)
+	lg.Log(logging.Entry{Severity: logging.Info, Payload: "info entry"})
+	lg.Log(logging.Entry{Severity: logging.Info, Payload: "info entry"})
+	_ = lg.LogSync(context.Background(), logging.Entry{Severity: logging.Error, Payload: "error entry"})
+
+	el := NewMetricsLogger(errLogger{}, "failing", m)
+	if err := el.LogSync(context.Background(), logging.Entry{Severity: logging.Warning}); !errors.Is(err, errSink) {
+		t.Errorf("expected error to wrap %v, got %v", errSink, err)
+	}
+	m.AddDropped("", DropOverflow)
+
+	want := MetricsSnapshot{
+		Logged: []LoggedCount{
+			{"app", logging.Info, 2},
+			{"app", logging.Error, 1},
+			{"failing", logging.Warning, 1},
+		},
+		Dropped:     []DroppedCount{{"", DropOverflow, 1}},
+		WriteErrors: 1,
+	}
+	if got := m.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("expected snapshot %+v, got %+v", want, got)
+	}
+}
+
+func TestFilterClient(t *testing.T) {
+	before := DefaultMetrics.Dropped("filter-test", DropFiltered)
+
+	lg := NewFilterClient(NewStandardClient(ioutil.Discard), logging.Warning).Logger("filter-test")
+	lg.Log(logging.Entry{Severity: logging.Info})
+	lg.Log(logging.Entry{Severity: logging.Error})
+
+	if got := DefaultMetrics.Dropped("filter-test", DropFiltered) - before; got != 1 {
+		t.Errorf("expected 1 filtered entry, got %d", got)
+	}
+	if err := lg.LogSync(context.Background(), logging.Entry{Severity: logging.Info}); !errors.Is(err, ErrFiltered) {
+		t.Errorf("expected filtered LogSync to return ErrFiltered, got %v", err)
+	}
+
+	lg = NewThresholdClient(NewStandardClient(ioutil.Discard), logging.Warning).Logger("threshold-test")
+	lg.Log(logging.Entry{Severity: logging.Info})
+
+	if got := DefaultMetrics.Dropped("threshold-test", DropFiltered); got != 0 {
+		t.Errorf("expected threshold client entries not to be counted, got %d", got)
+	}
+	if err := lg.LogSync(context.Background(), logging.Entry{Severity: logging.Info}); err != nil {
+		t.Errorf("expected threshold client LogSync not to fail, got %v", err)
+	}
+}
+
+func TestMetrics_ServeHTTP(t *testing.T) {
+	m := NewMetrics()
+	m.AddLogged(`a"b`, logging.Notice)
+	m.AddDropped("app", DropSampled)
+
+	rec := httptest.NewRecorder()
+	m.ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("unexpected content type %q", ct)
+	}
+
+	for _, line := range []string{
+		`gke_log_entries_total{log_id="a\"b",severity="NOTICE"} 1`,
+		`gke_log_entries_dropped_total{log_id="app",reason="sampler"} 1`,
+		`gke_log_write_errors_total 0`,
+	} {
+		if !bytes.Contains(rec.Body.Bytes(), []byte(line+"\n")) {
+			t.Errorf("expected line %q in:\n%s", line, rec.Body.String())
+		}
+	}
+}
@@ -1,5 +1,3 @@
-// +build false
-
 /*
 Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
 
@@ -21,16 +19,21 @@ package log
 
 import (
 	"cloud.google.com/go/logging"
+	"context"
+	"errors"
 	"fmt"
-	"io"
+	"io/ioutil"
 	"log"
 	"strings"
 )
 
 // MultiClient wraps multiple clients. Each operation on a MultiClient
-// is executed on each of its underlying clients.
+// is executed on each of its underlying clients in order. An error or panic
+// from one client does not prevent the operation from being executed on the
+// others, but a client that blocks delays the clients after it.
 type MultiClient []Client
 
+// Close implements log.Client.Close().
 func (mc MultiClient) Close() error {
 	var es errs
 	for _, l := range mc {
@@ -47,6 +50,7 @@ func (mc MultiClient) Close() error {
 	return nil
 }
 
+// Logger implements log.Client.Logger().
 func (mc MultiClient) Logger(logID string) Logger {
 	result := MultiLogger{make([]Logger, 0, len(mc))}
 	for _, c := range mc {
@@ -61,24 +65,48 @@ type MultiLogger struct {
 	ls []Logger
 }
 
+// StandardLogger implements log.Logger.StandardLogger().
 func (m MultiLogger) StandardLogger(severity logging.Severity) *log.Logger {
-	writers := make([]io.Writer, 0, len(m.ls))
+	return NewStandardLogger(m, severity)
+}
+
+// Log implements log.Logger.Log().
+func (m MultiLogger) Log(entry logging.Entry) {
+	SetupSourceLocation(&entry, 1)
 	for _, l := range m.ls {
-		writers = append(writers, l.StandardLogger(severity).Writer())
+		_ = isolate(func() error {
+			l.Log(entry)
+			return nil
+		})
 	}
-	return log.New(io.MultiWriter(writers...), severity.String(), log.LstdFlags)
 }
 
-func (m MultiLogger) Log(entry logging.Entry) {
+// LogSync implements log.Logger.LogSync().
+func (m MultiLogger) LogSync(ctx context.Context, entry logging.Entry) error {
+	SetupSourceLocation(&entry, 1)
+
+	var es errs
 	for _, l := range m.ls {
-		l.Log(entry)
+		err := isolate(func() error {
+			return l.LogSync(ctx, entry)
+		})
+		if err != nil {
+			es = append(es, err)
+		}
+	}
+
+	if len(es) > 0 {
+		return fmt.Errorf("1 or more errors while logging entry: %w", es)
 	}
+
+	return nil
 }
 
+// Flush implements log.Logger.Flush().
 func (m MultiLogger) Flush() error {
 	var es errs
 	for _, l := range m.ls {
-		err := l.Flush()
+		err := isolate(l.Flush)
 		if err != nil {
 			es = append(es, err)
 		}
@@ -91,6 +119,18 @@ func (m MultiLogger) Flush() error {
 	return nil
 }
 
+// isolate calls f, converting a panic into an error so that a single
+// misbehaving logger cannot prevent the others from being called.
+func isolate(f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("log sink panicked: %v", r)
+			_, _ = fmt.Fprintf(Stderr, "gke: %v\n", err)
+		}
+	}()
+	return f()
+}
+
 type errs []error
 
 func (e errs) Error() string {
@@ -103,3 +143,79 @@ func (e errs) Error() string {
 	}
 	return builder.String()
 }
+
+// Unwrap returns the underlying errors.
+func (e errs) Unwrap() []error {
+	return e
+}
+
+// NewThresholdClient returns a client whose loggers discard entries with a
+// severity less than minSeverity.
+func NewThresholdClient(c Client, minSeverity logging.Severity) Client {
+	return thresholdClient{c, minSeverity, false}
+}
+
+// ErrFiltered is returned by LogSync() of loggers returned by a client from
+// NewFilterClient() when the entry is below the minimum severity.
+var ErrFiltered = errors.New("entry is below the minimum severity")
+
+// NewFilterClient is like NewThresholdClient(), but discarded entries are counted
+// in DefaultMetrics as DropFiltered, and LogSync() returns an error wrapping
+// ErrFiltered for them. NewThresholdClient() is meant for sinks that receive a
+// subset of entries by design, so their discarded entries are not reported.
+func NewFilterClient(c Client, minSeverity logging.Severity) Client {
+	return thresholdClient{c, minSeverity, true}
+}
+
+type thresholdClient struct {
+	Client
+	minSeverity logging.Severity
+	countDrops  bool
+}
+
+// Logger implements log.Client.Logger().
+func (t thresholdClient) Logger(logID string) Logger {
+	result := thresholdLogger{Logger: t.Client.Logger(logID), minSeverity: t.minSeverity}
+	if t.countDrops {
+		result.metrics, result.logID = DefaultMetrics, logID
+	}
+	return result
+}
+
+type thresholdLogger struct {
+	Logger
+	minSeverity logging.Severity
+	metrics     *Metrics // nil if discarded entries are not counted
+	logID       string
+}
+
+// StandardLogger implements log.Logger.StandardLogger().
+func (t thresholdLogger) StandardLogger(severity logging.Severity) *log.Logger {
+	if severity < t.minSeverity {
+		return log.New(ioutil.Discard, "", 0)
+	}
+	return t.Logger.StandardLogger(severity)
+}
+
+// Log implements log.Logger.Log().
+func (t thresholdLogger) Log(entry logging.Entry) {
+	if entry.Severity < t.minSeverity {
+		t.metrics.AddDropped(t.logID, DropFiltered)
+		return
+	}
+	SetupSourceLocation(&entry, 1)
+	t.Logger.Log(entry)
+}
+
+// LogSync implements log.Logger.LogSync().
+func (t thresholdLogger) LogSync(ctx context.Context, entry logging.Entry) error {
+	if entry.Severity < t.minSeverity {
+		if t.metrics == nil {
+			return nil
+		}
+		t.metrics.AddDropped(t.logID, DropFiltered)
+		return fmt.Errorf("failed to log %v entry: %w", entry.Severity, ErrFiltered)
+	}
+	SetupSourceLocation(&entry, 1)
+	return t.Logger.LogSync(ctx, entry)
+}
@@ -0,0 +1,87 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"bytes"
+	"cloud.google.com/go/logging"
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestMultiLogger_Log(t *testing.T) {
+	var all, errsOnly bytes.Buffer
+	mc := MultiClient{
+		NewStandardClient(&all),
+		NewThresholdClient(NewStandardClient(&errsOnly), logging.Error),
+		panicClient{},
+	}
+
+	lg := mc.Logger("test")
+	lg.Log(logging.Entry{Severity: logging.Info, Payload: "info entry"})
+	lg.Log(logging.Entry{Severity: logging.Error, Payload: "error entry"})
+
+	if got := strings.Count(all.String(), "\n"); got != 2 {
+		t.Errorf("expected 2 entries in unfiltered sink, got %d: %q", got, all.String())
+	}
+	if strings.Contains(errsOnly.String(), "info entry") || !strings.Contains(errsOnly.String(), "error entry") {
+		t.Errorf("expected only the error entry in filtered sink, got %q", errsOnly.String())
+	}
+}
+
+func TestMultiLogger_LogSync(t *testing.T) {
+	var buf bytes.Buffer
+	mc := MultiClient{panicClient{}, NewStandardClient(&buf), errClient{}}
+
+	err := mc.Logger("test").LogSync(context.Background(), logging.Entry{Severity: logging.Warning, Payload: "warning entry"})
+	if !errors.Is(err, errSink) {
+		t.Errorf("expected error to wrap %v, got %v", errSink, err)
+	}
+	if !strings.Contains(buf.String(), "warning entry") {
+		t.Errorf("expected entry to be written despite failing sinks, got %q", buf.String())
+	}
+}
+
+var errSink = errors.New("sink failed")
+
+type panicClient struct{}
+
+func (panicClient) Logger(string) Logger { return panicLogger{} }
+func (panicClient) Close() error         { return nil }
+
+type panicLogger struct{}
+
+func (panicLogger) StandardLogger(logging.Severity) *log.Logger  { panic("not implemented") }
+func (panicLogger) Log(logging.Entry)                            { panic("sink failed") }
+func (panicLogger) Flush() error                                 { panic("sink failed") }
+func (panicLogger) LogSync(context.Context, logging.Entry) error { panic("sink failed") }
+
+type errClient struct{}
+
+func (errClient) Logger(string) Logger { return errLogger{} }
+func (errClient) Close() error         { return errSink }
+
+type errLogger struct{}
+
+func (errLogger) StandardLogger(logging.Severity) *log.Logger  { panic("not implemented") }
+func (errLogger) Log(logging.Entry)                            {}
+func (errLogger) Flush() error                                 { return errSink }
+func (errLogger) LogSync(context.Context, logging.Entry) error { return errSink }
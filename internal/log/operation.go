@@ -0,0 +1,63 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	logpb "google.golang.org/genproto/googleapis/logging/v2"
+	"log"
+)
+
+// NewOperationLogger returns a Logger that stamps each entry with an operation
+// identified by id and producer before passing it on to l. Entries that already
+// have an operation set are left unchanged.
+func NewOperationLogger(l Logger, id, producer string) Logger {
+	return operationLogger{l, id, producer}
+}
+
+type operationLogger struct {
+	Logger
+	id       string
+	producer string
+}
+
+func (o operationLogger) setupOperation(entry *logging.Entry) {
+	if entry.Operation == nil {
+		entry.Operation = &logpb.LogEntryOperation{Id: o.id, Producer: o.producer}
+	}
+}
+
+// StandardLogger implements log.Logger.StandardLogger().
+func (o operationLogger) StandardLogger(severity logging.Severity) *log.Logger {
+	return NewStandardLogger(o, severity)
+}
+
+// Log implements log.Logger.Log().
+func (o operationLogger) Log(entry logging.Entry) {
+	SetupSourceLocation(&entry, 1)
+	o.setupOperation(&entry)
+	o.Logger.Log(entry)
+}
+
+// LogSync implements log.Logger.LogSync().
+func (o operationLogger) LogSync(ctx context.Context, entry logging.Entry) error {
+	SetupSourceLocation(&entry, 1)
+	o.setupOperation(&entry)
+	return o.Logger.LogSync(ctx, entry)
+}
@@ -0,0 +1,196 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"bytes"
+	"cloud.google.com/go/logging"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"log"
+	"reflect"
+	"time"
+	"unicode/utf8"
+)
+
+// MaxPayloadBytes is the size above which payloads are truncated by NormalizePayload().
+// It leaves room for the rest of the entry under the 256 KB Cloud Logging entry limit.
+var MaxPayloadBytes = 250 * 1024
+
+// NormalizePayload converts a payload into a form that can always be written to Cloud
+// Logging: nil, a string (text payload) or a json.RawMessage holding a JSON object
+// (JSON payload).
+//
+// Errors are converted to their message ("<nil>" for nil pointers), fmt.Stringer values that do not implement
+// json.Marshaler to their string, byte slices to a string (base64 encoded if they are
+// not valid UTF-8) and time.Time values to RFC 3339 strings. The fields of a
+// map[string]interface{} payload are converted in the same way. Other values are marshaled
+// to JSON. JSON values that are not objects are stored in the "value" field of an object.
+//
+// Payloads that cannot be marshaled to JSON, such as channels, funcs and cyclic values,
+// are replaced with a string describing the failure. Payloads larger than MaxPayloadBytes
+// are truncated to a string ending with a marker that records the number of bytes removed.
+func NormalizePayload(payload interface{}) interface{} {
+	switch p := payload.(type) {
+	case nil:
+		return nil
+	case string:
+		return truncatePayload(p)
+	case json.RawMessage:
+		return normalizeJSON(p)
+	case json.Number:
+		return normalizeJSON([]byte(p))
+	case error:
+		if _, ok := p.(json.Marshaler); !ok {
+			if isNilPointer(p) {
+				return "<nil>"
+			}
+			return truncatePayload(p.Error())
+		}
+	case time.Time:
+		return truncatePayload(p.Format(time.RFC3339Nano))
+	case []byte:
+		if utf8.Valid(p) {
+			return truncatePayload(string(p))
+		}
+		return truncatePayload(base64.StdEncoding.EncodeToString(p))
+	case fmt.Stringer:
+		if _, ok := p.(json.Marshaler); !ok {
+			if isNilPointer(p) {
+				return "<nil>"
+			}
+			return truncatePayload(p.String())
+		}
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(p))
+		for k, v := range p {
+			m[k] = normalizeValue(v)
+		}
+		payload = m
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Sprintf("failed to serialize payload of type %T: %v", payload, err)
+	}
+	return normalizeJSON(b)
+}
+
+func normalizeJSON(b []byte) interface{} {
+	b = bytes.TrimSpace(b)
+	switch {
+	case len(b) == 0 || bytes.Equal(b, []byte("null")):
+		return nil
+	case b[0] == '"':
+		var s string
+		if err := json.Unmarshal(b, &s); err == nil {
+			return truncatePayload(s)
+		}
+	case b[0] != '{':
+		b = append(append([]byte(`{"value":`), b...), '}')
+	}
+
+	if !json.Valid(b) {
+		return "failed to serialize payload: invalid JSON"
+	}
+	if len(b) > MaxPayloadBytes {
+		return truncatePayload(string(b))
+	}
+	return json.RawMessage(b)
+}
+
+// normalizeValue converts a field of a map payload in the same way as NormalizePayload(),
+// except that values are not marshaled. Values of kinds that cannot be marshaled to JSON
+// are replaced with a string describing them so that the rest of the payload is kept.
+func normalizeValue(v interface{}) interface{} {
+	switch x := v.(type) {
+	case json.Marshaler, json.Number:
+		return v
+	case error:
+		if isNilPointer(x) {
+			return "<nil>"
+		}
+		return x.Error()
+	case fmt.Stringer:
+		if isNilPointer(x) {
+			return "<nil>"
+		}
+		return x.String()
+	}
+
+	switch reflect.ValueOf(v).Kind() {
+	case reflect.Chan, reflect.Func, reflect.Complex64, reflect.Complex128, reflect.UnsafePointer:
+		return fmt.Sprintf("unsupported value of type %T", v)
+	}
+	return v
+}
+
+// isNilPointer reports whether v holds a nil pointer. Calling the methods of such
+// a value panics unless they are written to handle a nil receiver.
+func isNilPointer(v interface{}) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Ptr && rv.IsNil()
+}
+
+// truncatePayload truncates s to MaxPayloadBytes, including a marker that records the
+// number of bytes removed. It does not split UTF-8 encoded runes.
+func truncatePayload(s string) string {
+	if len(s) <= MaxPayloadBytes {
+		return s
+	}
+
+	n := MaxPayloadBytes - len("... [truncated 0000000000 bytes]")
+	if n < 0 {
+		n = 0
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return fmt.Sprintf("%s... [truncated %d bytes]", s[:n], len(s)-n)
+}
+
+// NewNormalizingLogger returns a logger that normalizes the payload of each entry
+// with NormalizePayload() before it is passed to l.
+func NewNormalizingLogger(l Logger) Logger {
+	return normalizingLogger{l}
+}
+
+type normalizingLogger struct {
+	Logger
+}
+
+// StandardLogger implements log.Logger.StandardLogger().
+func (n normalizingLogger) StandardLogger(severity logging.Severity) *log.Logger {
+	return NewStandardLogger(n, severity)
+}
+
+// Log implements log.Logger.Log().
+func (n normalizingLogger) Log(entry logging.Entry) {
+	SetupSourceLocation(&entry, 1)
+	entry.Payload = NormalizePayload(entry.Payload)
+	n.Logger.Log(entry)
+}
+
+// LogSync implements log.Logger.LogSync().
+func (n normalizingLogger) LogSync(ctx context.Context, entry logging.Entry) error {
+	SetupSourceLocation(&entry, 1)
+	entry.Payload = NormalizePayload(entry.Payload)
+	return n.Logger.LogSync(ctx, entry)
+}
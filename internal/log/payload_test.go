@@ -0,0 +1,106 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type cyclic struct {
+	Next *cyclic
+}
+
+type stringer struct{}
+
+func (stringer) String() string { return "stringer" }
+
+type nilError struct{ msg string }
+
+func (e *nilError) Error() string { return e.msg }
+
+type nilStringer struct{ s string }
+
+func (s *nilStringer) String() string { return s.s }
+
+func TestNormalizePayload(t *testing.T) {
+	c := &cyclic{}
+	c.Next = c
+
+	for _, tc := range []struct {
+		name    string
+		payload interface{}
+		want    interface{}
+	}{
+		{"nil", nil, nil},
+		{"string", "text", "text"},
+		{"error", errors.New("failed"), "failed"},
+		{"stringer", stringer{}, "stringer"},
+		{"typed nil error", (*nilError)(nil), "<nil>"},
+		{"typed nil stringer", (*nilStringer)(nil), "<nil>"},
+		{"map typed nil fields", map[string]interface{}{"err": (*nilError)(nil), "s": (*nilStringer)(nil)}, json.RawMessage(`{"err":"\u003cnil\u003e","s":"\u003cnil\u003e"}`)},
+		{"bytes", []byte("text"), "text"},
+		{"binary bytes", []byte{0xff, 0xfe}, "//4="},
+		{"time", time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC), "2020-01-02T03:04:05Z"},
+		{"duration", 1500 * time.Millisecond, "1.5s"},
+		{"struct", struct{ A int }{1}, json.RawMessage(`{"A":1}`)},
+		{"number", 42, json.RawMessage(`{"value":42}`)},
+		{"json number", json.Number("4611686018427387905"), json.RawMessage(`{"value":4611686018427387905}`)},
+		{"map json number", map[string]interface{}{"id": json.Number("4611686018427387905")}, json.RawMessage(`{"id":4611686018427387905}`)},
+		{"map fields", map[string]interface{}{"err": errors.New("failed"), "ch": make(chan int)}, json.RawMessage(`{"ch":"unsupported value of type chan int","err":"failed"}`)},
+		{"chan", make(chan int), "failed to serialize payload of type chan int: json: unsupported type: chan int"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got := NormalizePayload(tc.payload)
+			if gb, ok := got.(json.RawMessage); ok {
+				if wb, ok := tc.want.(json.RawMessage); !ok || string(gb) != string(wb) {
+					t.Errorf("expected %v, got %s", tc.want, gb)
+				}
+				return
+			}
+			if got != tc.want {
+				t.Errorf("expected %#v, got %#v", tc.want, got)
+			}
+		})
+	}
+
+	if got, ok := NormalizePayload(c).(string); !ok || !strings.HasPrefix(got, "failed to serialize payload of type *log.cyclic: ") {
+		t.Errorf("expected a serialization failure for a cyclic value, got %#v", got)
+	}
+}
+
+func TestNormalizePayload_truncate(t *testing.T) {
+	defer func(n int) { MaxPayloadBytes = n }(MaxPayloadBytes)
+	MaxPayloadBytes = 64
+
+	got, ok := NormalizePayload(strings.Repeat("é", 100)).(string)
+	if !ok || len(got) > MaxPayloadBytes || !strings.HasSuffix(got, " bytes]") {
+		t.Fatalf("expected truncated string of at most %d bytes, got %q", MaxPayloadBytes, got)
+	}
+	if !strings.HasPrefix(got, "éé") || strings.ContainsRune(got, '�') {
+		t.Errorf("expected truncation on a rune boundary, got %q", got)
+	}
+
+	got, ok = NormalizePayload(map[string]interface{}{"message": strings.Repeat("a", 100)}).(string)
+	if !ok || !strings.HasPrefix(got, `{"message":"aaa`) || !strings.Contains(got, "truncated") {
+		t.Errorf("expected oversize JSON to be truncated to a string, got %#v", got)
+	}
+}
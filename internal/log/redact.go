@@ -0,0 +1,200 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"bytes"
+	"cloud.google.com/go/logging"
+	"context"
+	"encoding/json"
+	"log"
+	"regexp"
+	"strings"
+)
+
+// Redacted replaces secrets removed by a Redactor.
+const Redacted = "[REDACTED]"
+
+// Redactor removes secrets from log payloads. The zero value redacts nothing.
+type Redactor struct {
+	// KeyPatterns are case-insensitive patterns matched against map keys and
+	// environment variable names. The only special character is '*', which
+	// matches any sequence of characters. Values of matching keys are replaced
+	// with Redacted.
+	KeyPatterns []string
+	// ValuePatterns are matched against string values. Matches are replaced with Redacted.
+	ValuePatterns []*regexp.Regexp
+}
+
+// MatchKey reports whether key matches one of r.KeyPatterns.
+func (r *Redactor) MatchKey(key string) bool {
+	key = strings.ToUpper(key)
+	for _, p := range r.KeyPatterns {
+		if matchWildcard(strings.ToUpper(p), key) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactString replaces each match of r.ValuePatterns in s with Redacted.
+func (r *Redactor) RedactString(s string) string {
+	for _, p := range r.ValuePatterns {
+		s = p.ReplaceAllLiteralString(s, Redacted)
+	}
+	return s
+}
+
+// RedactEnv redacts a list of KEY=VALUE strings, such as the one returned by os.Environ().
+func (r *Redactor) RedactEnv(env []string) []string {
+	result := make([]string, len(env))
+	for i, kv := range env {
+		k := kv
+		if eq := strings.IndexByte(kv, '='); eq >= 0 {
+			k = kv[:eq]
+		}
+		if r.MatchKey(k) {
+			result[i] = k + "=" + Redacted
+			continue
+		}
+		result[i] = r.RedactString(kv)
+	}
+	return result
+}
+
+// Redact returns payload with secrets removed. Strings are redacted with RedactString().
+// Other payloads are converted to their JSON representation (e.g. structs become
+// map[string]interface{}) so that their fields can be inspected. If payload cannot
+// be converted, then it is returned unchanged.
+func (r *Redactor) Redact(payload interface{}) interface{} {
+	switch p := payload.(type) {
+	case nil:
+		return nil
+	case string:
+		return r.RedactString(p)
+	}
+
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return payload
+	}
+
+	// Numbers are kept as json.Number so that IDs and counters are not rounded.
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var v interface{}
+	err = d.Decode(&v)
+	if err != nil {
+		return payload
+	}
+
+	return r.redactValue(v)
+}
+
+func (r *Redactor) redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case string:
+		return r.RedactString(v)
+	case []interface{}:
+		for i, e := range v {
+			v[i] = r.redactValue(e)
+		}
+		return v
+	case map[string]interface{}:
+		for k, e := range v {
+			if r.MatchKey(k) {
+				v[k] = Redacted
+				continue
+			}
+			v[k] = r.redactValue(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// matchWildcard reports whether s matches pattern, where '*' in pattern matches
+// any sequence of characters.
+func matchWildcard(pattern, s string) bool {
+	parts := strings.Split(pattern, "*")
+	if len(parts) == 1 {
+		return pattern == s
+	}
+
+	if !strings.HasPrefix(s, parts[0]) {
+		return false
+	}
+	s = s[len(parts[0]):]
+
+	last := parts[len(parts)-1]
+	for _, part := range parts[1 : len(parts)-1] {
+		i := strings.Index(s, part)
+		if i < 0 {
+			return false
+		}
+		s = s[i+len(part):]
+	}
+
+	return len(s) >= len(last) && strings.HasSuffix(s, last)
+}
+
+// NewRedactingLogger returns a logger that removes secrets from the payload
+// and labels of each entry with r before passing it on to l.
+func NewRedactingLogger(l Logger, r *Redactor) Logger {
+	return redactingLogger{l, r}
+}
+
+type redactingLogger struct {
+	Logger
+	redactor *Redactor
+}
+
+func (r redactingLogger) redact(entry *logging.Entry) {
+	entry.Payload = r.redactor.Redact(entry.Payload)
+	if len(entry.Labels) == 0 {
+		return
+	}
+	labels := make(map[string]string, len(entry.Labels))
+	for k, v := range entry.Labels {
+		if r.redactor.MatchKey(k) {
+			v = Redacted
+		}
+		labels[k] = r.redactor.RedactString(v)
+	}
+	entry.Labels = labels
+}
+
+// StandardLogger implements log.Logger.StandardLogger().
+func (r redactingLogger) StandardLogger(severity logging.Severity) *log.Logger {
+	return NewStandardLogger(r, severity)
+}
+
+// Log implements log.Logger.Log().
+func (r redactingLogger) Log(entry logging.Entry) {
+	SetupSourceLocation(&entry, 1)
+	r.redact(&entry)
+	r.Logger.Log(entry)
+}
+
+// LogSync implements log.Logger.LogSync().
+func (r redactingLogger) LogSync(ctx context.Context, entry logging.Entry) error {
+	SetupSourceLocation(&entry, 1)
+	r.redact(&entry)
+	return r.Logger.LogSync(ctx, entry)
+}
@@ -0,0 +1,142 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"fmt"
+	"log"
+	"reflect"
+)
+
+// Route dispatches matching entries to Logger. An entry matches a route
+// if it matches every one of the route's non-zero conditions.
+type Route struct {
+	// MinSeverity is the minimum severity of matching entries.
+	MinSeverity logging.Severity
+	// MaxSeverity is the maximum severity of matching entries. If it is
+	// logging.Default, then there is no maximum.
+	MaxSeverity logging.Severity
+	// Labels must each be present on matching entries with the same value.
+	// An empty value matches any value for the label.
+	Labels map[string]string
+	// PayloadType is the type of the payload of matching entries. If it is
+	// an interface type, then payloads that implement it match.
+	PayloadType reflect.Type
+	// Logger receives matching entries.
+	Logger Logger
+	// LogID identifies Logger. Routes with the same non-empty LogID must share
+	// a logger, which is flushed once. If it is empty, then Logger is always flushed.
+	LogID string
+}
+
+// Match returns true if entry matches the route's conditions.
+func (r Route) Match(entry logging.Entry) bool {
+	if entry.Severity < r.MinSeverity {
+		return false
+	}
+
+	if r.MaxSeverity != logging.Default && entry.Severity > r.MaxSeverity {
+		return false
+	}
+
+	for k, v := range r.Labels {
+		ev, ok := entry.Labels[k]
+		if !ok || v != "" && v != ev {
+			return false
+		}
+	}
+
+	if r.PayloadType != nil {
+		pt := reflect.TypeOf(entry.Payload)
+		if pt == nil {
+			return false
+		}
+		if r.PayloadType.Kind() == reflect.Interface {
+			return pt.Implements(r.PayloadType)
+		}
+		return pt == r.PayloadType
+	}
+
+	return true
+}
+
+// NewRoutingLogger returns a logger that dispatches each entry to the Logger of
+// the first route that matches it. Unmatched entries are dispatched to def,
+// which is identified by defLogID (see Route.LogID).
+func NewRoutingLogger(defLogID string, def Logger, routes ...Route) Logger {
+	return routingLogger{Route{Logger: def, LogID: defLogID}, routes}
+}
+
+type routingLogger struct {
+	def    Route
+	routes []Route
+}
+
+func (r routingLogger) route(entry logging.Entry) Logger {
+	for _, rt := range r.routes {
+		if rt.Match(entry) {
+			return rt.Logger
+		}
+	}
+	return r.def.Logger
+}
+
+// StandardLogger implements log.Logger.StandardLogger().
+func (r routingLogger) StandardLogger(severity logging.Severity) *log.Logger {
+	return NewStandardLogger(r, severity)
+}
+
+// Log implements log.Logger.Log().
+func (r routingLogger) Log(entry logging.Entry) {
+	SetupSourceLocation(&entry, 1)
+	r.route(entry).Log(entry)
+}
+
+// LogSync implements log.Logger.LogSync().
+func (r routingLogger) LogSync(ctx context.Context, entry logging.Entry) error {
+	SetupSourceLocation(&entry, 1)
+	return r.route(entry).LogSync(ctx, entry)
+}
+
+// Flush implements log.Logger.Flush(). Loggers shared by
+// multiple routes with the same LogID are flushed once.
+func (r routingLogger) Flush() error {
+	seen := make(map[string]bool, 1+len(r.routes))
+	var es errs
+	for _, rt := range append([]Route{r.def}, r.routes...) {
+		if rt.LogID != "" {
+			if seen[rt.LogID] {
+				continue
+			}
+			seen[rt.LogID] = true
+		}
+
+		err := rt.Logger.Flush()
+		if err != nil {
+			es = append(es, err)
+		}
+	}
+
+	if len(es) > 0 {
+		return fmt.Errorf("1 or more errors while flushing logger: %w", es)
+	}
+
+	return nil
+}
@@ -0,0 +1,286 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"fmt"
+	logpb "google.golang.org/genproto/googleapis/logging/v2"
+	"log"
+	"sync"
+	"time"
+)
+
+// SamplingConfig configures the sampling done by a logger returned by NewSamplingLogger().
+type SamplingConfig struct {
+	// Tick is the length of each sampling window. If 0 or negative, then
+	// 1 second is used.
+	Tick time.Duration
+	// First is the number of entries from each call site that are logged
+	// during each window before sampling begins. Negative values are treated
+	// as 0. If First and Thereafter are both 0, which would suppress every
+	// entry, then 100 is used.
+	First int
+	// Thereafter causes every Thereafter-th entry after First to be logged. If
+	// Thereafter is 0 or negative, then all entries after First are suppressed.
+	Thereafter int
+}
+
+const (
+	defaultSamplingTick  = time.Second
+	defaultSamplingFirst = 100
+)
+
+// withDefaults returns a copy of cfg with invalid values replaced by their defaults.
+func (cfg SamplingConfig) withDefaults() SamplingConfig {
+	if cfg.Tick <= 0 {
+		cfg.Tick = defaultSamplingTick
+	}
+	if cfg.First < 0 {
+		cfg.First = 0
+	}
+	if cfg.Thereafter < 0 {
+		cfg.Thereafter = 0
+	}
+	if cfg.First == 0 && cfg.Thereafter == 0 {
+		cfg.First = defaultSamplingFirst
+	}
+	return cfg
+}
+
+// NewSamplingLogger returns a logger that limits the number of entries logged
+// from each call site. Call sites are identified by source location (see
+// SetupSourceLocation()), or by program counter if source locations are not
+// captured. When a window ends, the number of suppressed entries is reported
+// with a Warning entry for that call site. While entries are being suppressed,
+// a ticker reports the windows that have ended every cfg.Tick, even if the call site stops
+// logging. Pending reports are also written by Flush(), which stops the ticker
+// until entries are suppressed again. While the ticker is running, the logger is
+// added to pending, which may be nil. Entries logged with LogSync() are never suppressed.
+func NewSamplingLogger(l Logger, cfg SamplingConfig, pending *LoggerSet) Logger {
+	return newSamplingLogger(l, "", cfg, pending)
+}
+
+func newSamplingLogger(l Logger, logID string, cfg SamplingConfig, active *LoggerSet) Logger {
+	return &samplingLogger{Logger: l, logID: logID, cfg: cfg.withDefaults(), active: active, sites: make(map[siteKey]*sampleSite)}
+}
+
+type samplingLogger struct {
+	Logger
+	logID  string // used for metrics; empty if unknown
+	cfg    SamplingConfig
+	active *LoggerSet // loggers with a running ticker; may be nil
+	mu     sync.Mutex // protects below
+	sites  map[siteKey]*sampleSite
+	stop   chan struct{} // closed to stop the ticker; nil if it is not running
+}
+
+type sampleSite struct {
+	key        siteKey
+	loc        *logpb.LogEntrySourceLocation // nil if not captured
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// StandardLogger implements log.Logger.StandardLogger().
+func (s *samplingLogger) StandardLogger(severity logging.Severity) *log.Logger {
+	return NewStandardLogger(s, severity)
+}
+
+// Log implements log.Logger.Log().
+func (s *samplingLogger) Log(entry logging.Entry) {
+	SetupSourceLocation(&entry, 1)
+
+	ok, report := s.sample(newSiteKey(entry), entry.SourceLocation, time.Now())
+	if report != nil {
+		s.Logger.Log(*report)
+	}
+	if ok {
+		s.Logger.Log(entry)
+	} else {
+		DefaultMetrics.AddDropped(s.logID, DropSampled)
+	}
+}
+
+// sample reports whether an entry from the call site identified by key should be
+// logged. loc is the source location of the entry, which may be nil. If a window has
+// ended with suppressed entries, then a report entry is returned as well.
+func (s *samplingLogger) sample(key siteKey, loc *logpb.LogEntrySourceLocation, now time.Time) (ok bool, report *logging.Entry) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	site, found := s.sites[key]
+	if !found {
+		site = &sampleSite{key: key, loc: loc, start: now}
+		s.sites[key] = site
+	}
+
+	if now.Sub(site.start) >= s.cfg.Tick {
+		report = site.report()
+		site.start, site.count, site.suppressed = now, 0, 0
+	}
+
+	site.count++
+	n := site.count - s.cfg.First
+	if n <= 0 || (s.cfg.Thereafter > 0 && n%s.cfg.Thereafter == 0) {
+		return true, report
+	}
+
+	site.suppressed++
+	s.startTicker()
+	return false, report
+}
+
+// startTicker starts the ticker that reports ended windows if it is not running.
+// s.mu must be held.
+func (s *samplingLogger) startTicker() {
+	if s.stop != nil {
+		return
+	}
+	s.stop = make(chan struct{})
+	s.active.Add(s)
+	go s.tick(s.stop)
+}
+
+// stopTicker stops the ticker if it is running. s.mu must be held.
+func (s *samplingLogger) stopTicker() {
+	if s.stop == nil {
+		return
+	}
+	close(s.stop)
+	s.stop = nil
+	s.active.Remove(s)
+}
+
+func (s *samplingLogger) tick(stop chan struct{}) {
+	t := time.NewTicker(s.cfg.Tick)
+	defer t.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-t.C:
+			reports, done := s.expire(now, stop)
+			for _, r := range reports {
+				s.Logger.Log(*r)
+			}
+			if done {
+				return
+			}
+		}
+	}
+}
+
+// expire removes the sites whose window has ended as of now and returns their
+// reports. If no entries are being suppressed, then the ticker is stopped and
+// done is true.
+func (s *samplingLogger) expire(now time.Time, stop chan struct{}) (reports []*logging.Entry, done bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.stop != stop {
+		return nil, true // stopped by Flush()
+	}
+
+	pending := false
+	for key, site := range s.sites {
+		if now.Sub(site.start) < s.cfg.Tick {
+			pending = pending || site.suppressed > 0
+			continue
+		}
+		if r := site.report(); r != nil {
+			reports = append(reports, r)
+		}
+		delete(s.sites, key)
+	}
+
+	if !pending {
+		s.stopTicker()
+		return reports, true
+	}
+	return reports, false
+}
+
+func (s *sampleSite) report() *logging.Entry {
+	if s.suppressed == 0 {
+		return nil
+	}
+	return &logging.Entry{
+		Severity: logging.Warning,
+		Payload: map[string]interface{}{
+			"message":    fmt.Sprintf("suppressed %d log entries from %s", s.suppressed, s.key),
+			"suppressed": s.suppressed,
+			"since":      s.start,
+		},
+		SourceLocation: s.loc,
+	}
+}
+
+// Flush implements log.Logger.Flush(). Pending reports of suppressed entries are
+// logged before the underlying logger is flushed, and the ticker is stopped.
+func (s *samplingLogger) Flush() error {
+	s.mu.Lock()
+	s.stopTicker()
+	var reports []*logging.Entry
+	for _, site := range s.sites {
+		if r := site.report(); r != nil {
+			reports = append(reports, r)
+			site.suppressed = 0
+		}
+	}
+	s.mu.Unlock()
+
+	for _, r := range reports {
+		s.Logger.Log(*r)
+	}
+	return s.Logger.Flush()
+}
+
+// LogSync implements log.Logger.LogSync().
+func (s *samplingLogger) LogSync(ctx context.Context, entry logging.Entry) error {
+	SetupSourceLocation(&entry, 1)
+	return s.Logger.LogSync(ctx, entry)
+}
+
+// NewSamplingClient returns a client whose loggers are wrapped with NewSamplingLogger().
+// Close flushes the loggers that have pending reports before closing c.
+func NewSamplingClient(c Client, cfg SamplingConfig) Client {
+	return samplingClient{c, cfg, NewLoggerSet()}
+}
+
+type samplingClient struct {
+	Client
+	cfg    SamplingConfig
+	active *LoggerSet
+}
+
+// Logger implements log.Client.Logger().
+func (s samplingClient) Logger(logID string) Logger {
+	return newSamplingLogger(s.Client.Logger(logID), logID, s.cfg, s.active)
+}
+
+// Close implements log.Client.Close().
+func (s samplingClient) Close() error {
+	for _, l := range s.active.List() {
+		_ = l.Flush()
+	}
+	return s.Client.Close()
+}
@@ -0,0 +1,120 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"bytes"
+	"cloud.google.com/go/logging"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestSamplingLogger_ticker(t *testing.T) {
+	var buf syncBuffer
+	lg := NewSamplingLogger(NewStandardClient(&buf).Logger("test"), SamplingConfig{Tick: 10 * time.Millisecond, First: 1}, nil)
+
+	for i := 0; i < 5; i++ {
+		lg.Log(logging.Entry{Severity: logging.Info, Payload: "hot loop"})
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for !strings.Contains(buf.String(), "suppressed 4 log entries") {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected a report without further logging, got %q", buf.String())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	sl := lg.(*samplingLogger)
+	deadline = time.Now().Add(time.Second)
+	for {
+		sl.mu.Lock()
+		stopped := sl.stop == nil
+		sl.mu.Unlock()
+		if stopped {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("expected the ticker to stop once nothing is suppressed")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestSamplingClient_Close(t *testing.T) {
+	var buf syncBuffer
+	c := NewSamplingClient(NewStandardClient(&buf), SamplingConfig{Tick: time.Hour, First: 1})
+	lg := c.Logger("test")
+
+	for i := 0; i < 3; i++ {
+		lg.Log(logging.Entry{Severity: logging.Info, Payload: "hot loop"})
+	}
+	if err := c.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(buf.String(), "suppressed 2 log entries") {
+		t.Errorf("expected pending report to be written on Close, got %q", buf.String())
+	}
+	if sl := lg.(*samplingLogger); sl.stop != nil {
+		t.Error("expected the ticker to be stopped on Close")
+	}
+}
+
+func TestSamplingConfig_withDefaults(t *testing.T) {
+	for _, tc := range []struct {
+		cfg, want SamplingConfig
+	}{
+		{SamplingConfig{}, SamplingConfig{Tick: time.Second, First: 100}},
+		{SamplingConfig{Tick: -1, First: -1, Thereafter: -1}, SamplingConfig{Tick: time.Second, First: 100}},
+		{SamplingConfig{Tick: time.Minute, Thereafter: 10}, SamplingConfig{Tick: time.Minute, Thereafter: 10}},
+		{SamplingConfig{Tick: time.Minute, First: 5}, SamplingConfig{Tick: time.Minute, First: 5}},
+	} {
+		if got := tc.cfg.withDefaults(); got != tc.want {
+			t.Errorf("expected %+v for %+v, got %+v", tc.want, tc.cfg, got)
+		}
+	}
+
+	var buf syncBuffer
+	lg := NewSamplingLogger(NewStandardClient(&buf).Logger("test"), SamplingConfig{}, nil)
+	for i := 0; i < 3; i++ {
+		lg.Log(logging.Entry{Payload: "entry"})
+	}
+	if n := strings.Count(buf.String(), "entry"); n != 3 || strings.Contains(buf.String(), "suppressed") {
+		t.Errorf("expected the zero config to log entries without suppressing them, got %q", buf.String())
+	}
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (s *syncBuffer) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.Write(p)
+}
+
+func (s *syncBuffer) String() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.buf.String()
+}
@@ -0,0 +1,245 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// SlogHandlerOptions configures a handler returned by NewSlogHandler().
+type SlogHandlerOptions struct {
+	// Level is the minimum level that is logged. If nil, then slog.LevelInfo is used.
+	Level slog.Leveler
+	// AttrsAsLabels causes attributes added with Handler.WithAttrs() to be
+	// logged as entry labels instead of payload fields. Label keys are
+	// qualified by the current group names (e.g. "group.key").
+	AttrsAsLabels bool
+}
+
+// NewSlogHandler returns a slog.Handler that writes records to l. The
+// record message is stored in the "message" field of the payload and
+// attributes are stored in fields of their own, nested by group. The
+// "message" field is reserved (see MessageKey), so the values of ungrouped
+// attributes with that key are stored in the BadKey field instead.
+func NewSlogHandler(l Logger, opts *SlogHandlerOptions) slog.Handler {
+	h := &slogHandler{logger: l}
+	if opts != nil {
+		h.opts = *opts
+	}
+	return h
+}
+
+type slogHandler struct {
+	logger Logger
+	opts   SlogHandlerOptions
+	groups []string
+	attrs  []groupedAttr
+	labels map[string]string
+}
+
+type groupedAttr struct {
+	groups []string
+	attr   slog.Attr
+}
+
+// Enabled implements slog.Handler.Enabled().
+func (h *slogHandler) Enabled(_ context.Context, level slog.Level) bool {
+	min := slog.LevelInfo
+	if h.opts.Level != nil {
+		min = h.opts.Level.Level()
+	}
+	return level >= min
+}
+
+// Handle implements slog.Handler.Handle().
+func (h *slogHandler) Handle(_ context.Context, r slog.Record) error {
+	payload := map[string]interface{}{MessageKey: r.Message}
+	for _, ga := range h.attrs {
+		addAttr(payload, ga.groups, ga.attr)
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		addAttr(payload, h.groups, a)
+		return true
+	})
+
+	entry := logging.Entry{
+		Timestamp: r.Time,
+		Severity:  SlogSeverity(r.Level),
+		Payload:   payload,
+	}
+
+	if len(h.labels) > 0 {
+		entry.Labels = make(map[string]string, len(h.labels))
+		for k, v := range h.labels {
+			entry.Labels[k] = v
+		}
+	}
+
+	if r.PC != 0 {
+		fs := runtime.CallersFrames([]uintptr{r.PC})
+		f, _ := fs.Next()
+		SetupSourceLocationFrame(&entry, f)
+	}
+
+	h.logger.Log(entry)
+	return nil
+}
+
+// WithAttrs implements slog.Handler.WithAttrs().
+func (h *slogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if len(attrs) == 0 {
+		return h
+	}
+
+	result := *h
+	if h.opts.AttrsAsLabels {
+		result.labels = make(map[string]string, len(h.labels)+len(attrs))
+		for k, v := range h.labels {
+			result.labels[k] = v
+		}
+		for _, a := range attrs {
+			addLabel(result.labels, strings.Join(h.groups, "."), a)
+		}
+		return &result
+	}
+
+	result.attrs = make([]groupedAttr, len(h.attrs), len(h.attrs)+len(attrs))
+	copy(result.attrs, h.attrs)
+	for _, a := range attrs {
+		result.attrs = append(result.attrs, groupedAttr{h.groups, a})
+	}
+	return &result
+}
+
+// WithGroup implements slog.Handler.WithGroup().
+func (h *slogHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+
+	result := *h
+	result.groups = make([]string, len(h.groups), len(h.groups)+1)
+	copy(result.groups, h.groups)
+	result.groups = append(result.groups, name)
+	return &result
+}
+
+// SlogSeverity maps a slog.Level to a logging.Severity.
+func SlogSeverity(level slog.Level) logging.Severity {
+	switch {
+	case level < slog.LevelInfo:
+		return logging.Debug
+	case level < slog.LevelInfo+2:
+		return logging.Info
+	case level < slog.LevelWarn:
+		return logging.Notice
+	case level < slog.LevelError:
+		return logging.Warning
+	case level < slog.LevelError+4:
+		return logging.Error
+	case level < slog.LevelError+8:
+		return logging.Critical
+	case level < slog.LevelError+12:
+		return logging.Alert
+	default:
+		return logging.Emergency
+	}
+}
+
+// addAttr adds a to the map nested under groups. Empty attributes are
+// ignored and groups without attributes are omitted.
+func addAttr(m map[string]interface{}, groups []string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		ga := a.Value.Group()
+		if len(ga) == 0 {
+			return
+		}
+		if a.Key != "" {
+			groups = append(groups[:len(groups):len(groups)], a.Key)
+		}
+		for _, a := range ga {
+			addAttr(m, groups, a)
+		}
+		return
+	}
+
+	if len(groups) == 0 && a.Key == MessageKey {
+		AddKeyValues(m, slogValue(a.Value)) // a value without a key is stored in the BadKey field
+		return
+	}
+
+	for _, g := range groups {
+		child, ok := m[g].(map[string]interface{})
+		if !ok {
+			child = map[string]interface{}{}
+			m[g] = child
+		}
+		m = child
+	}
+	m[a.Key] = slogValue(a.Value)
+}
+
+func addLabel(labels map[string]string, prefix string, a slog.Attr) {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return
+	}
+
+	key := a.Key
+	if prefix != "" && key != "" {
+		key = prefix + "." + key
+	} else if key == "" {
+		key = prefix
+	}
+
+	if a.Value.Kind() == slog.KindGroup {
+		for _, a := range a.Value.Group() {
+			addLabel(labels, key, a)
+		}
+		return
+	}
+
+	labels[key] = fmt.Sprintf("%v", slogValue(a.Value))
+}
+
+func slogValue(v slog.Value) interface{} {
+	switch v.Kind() {
+	case slog.KindDuration:
+		return v.Duration().String()
+	case slog.KindTime:
+		return v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			return err.Error()
+		}
+		return v.Any()
+	default:
+		return v.Any()
+	}
+}
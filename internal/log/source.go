@@ -0,0 +1,341 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"bytes"
+	"cloud.google.com/go/logging"
+	"encoding/json"
+	"go/build"
+	logpb "google.golang.org/genproto/googleapis/logging/v2"
+	"path"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// SourceLocationConfig configures how SetupSourceLocation() captures source locations.
+type SourceLocationConfig struct {
+	// Disabled disables capturing source locations. Source locations that are
+	// already set on an entry are kept. Sampling and deduplication loggers still
+	// group entries by call site, using program counters instead.
+	Disabled bool
+	// TrimPaths causes file paths to be trimmed to be relative to their module
+	// root or GOPATH (see TrimSourceLocation()) instead of absolute build paths.
+	TrimPaths bool
+	// StackSeverity causes a stack trace to be captured in the StackField field
+	// of the payload for entries with at least this severity (see withStack()).
+	// If it is logging.Default, then stack traces are not captured.
+	StackSeverity logging.Severity
+}
+
+// StackField is the payload field used for stack traces captured by SetupSourceLocation().
+// Cloud Error Reporting recognizes stack traces in this field.
+const StackField = "stack_trace"
+
+var pkgSourceLocationConfig atomic.Value // SourceLocationConfig
+
+// SetSourceLocationConfig sets the configuration used by SetupSourceLocation().
+// It affects all loggers in the process.
+func SetSourceLocationConfig(cfg SourceLocationConfig) {
+	pkgSourceLocationConfig.Store(cfg)
+}
+
+func sourceLocationConfig() SourceLocationConfig {
+	cfg, _ := pkgSourceLocationConfig.Load().(SourceLocationConfig)
+	return cfg
+}
+
+var (
+	pkgHelpers      sync.Map // map[string]bool of function names or package paths ending with "."
+	pkgHelpersCount int32
+)
+
+// RegisterHelper marks the function that calls RegisterHelper as a helper. It is
+// similar to testing.T.Helper(). SetupSourceLocation() skips helpers when capturing
+// source locations, so wrapper libraries can report their callers instead. If
+// callDepth is 0, then the caller of RegisterHelper is marked. If 1, then the caller
+// of that caller, etc, etc.
+func RegisterHelper(callDepth int) {
+	pc, _, _, ok := runtime.Caller(1 + callDepth)
+	if !ok {
+		return
+	}
+	fs := runtime.CallersFrames([]uintptr{pc})
+	f, _ := fs.Next()
+	registerHelper(f.Function)
+}
+
+// RegisterHelperPackage marks every function in the package with import path
+// pkgPath as a helper. See RegisterHelper().
+func RegisterHelperPackage(pkgPath string) {
+	registerHelper(pkgPath + ".")
+}
+
+func registerHelper(name string) {
+	if _, loaded := pkgHelpers.LoadOrStore(name, true); !loaded {
+		atomic.AddInt32(&pkgHelpersCount, 1)
+	}
+}
+
+func hasHelpers() bool {
+	return atomic.LoadInt32(&pkgHelpersCount) > 0
+}
+
+func isHelper(function string) bool {
+	if _, ok := pkgHelpers.Load(function); ok {
+		return true
+	}
+	_, ok := pkgHelpers.Load(funcPackage(function) + ".")
+	return ok
+}
+
+// funcPackage returns the import path of the package of a fully-qualified
+// function name, such as "github.com/a/b.(*T).M".
+func funcPackage(function string) string {
+	slash := strings.LastIndex(function, "/")
+	dot := strings.Index(function[slash+1:], ".")
+	if dot < 0 {
+		return function
+	}
+	return function[:slash+1+dot]
+}
+
+// setSourceLocation sets entry.SourceLocation to f. skipped is the number of
+// helper frames before f, and callersSkip is the skip argument that was passed to
+// runtime.Callers() by the caller of setSourceLocation.
+func setSourceLocation(entry *logging.Entry, f runtime.Frame, skipped, callersSkip int, cfg SourceLocationConfig) {
+	entry.SourceLocation = sourceLocation(f, cfg)
+	if !wantsStack(entry, cfg) {
+		return
+	}
+
+	var pcs [64]uintptr
+	n := runtime.Callers(1+callersSkip, pcs[:])
+	entry.Payload = withStack(entry.Payload, formatStack(pcs[:n], skipped, cfg))
+}
+
+// SetupSourceLocationFrame sets up the entry.SourceLocation field from f if it is not
+// already set. It is used when the call site is already known, such as the program
+// counter of a slog.Record. Like SetupSourceLocation(), it is configured with
+// SetSourceLocationConfig(). If a stack trace is captured, then it starts at f, or
+// is omitted if f is not on the stack of the calling goroutine.
+func SetupSourceLocationFrame(entry *logging.Entry, f runtime.Frame) {
+	if entry.SourceLocation != nil {
+		return
+	}
+
+	cfg := sourceLocationConfig()
+	if cfg.Disabled {
+		return
+	}
+
+	entry.SourceLocation = sourceLocation(f, cfg)
+	if !wantsStack(entry, cfg) {
+		return
+	}
+
+	var pcs [64]uintptr
+	n := runtime.Callers(2, pcs[:])
+	fs := runtime.CallersFrames(pcs[:n])
+	for skipped := 0; ; skipped++ {
+		sf, more := fs.Next()
+		if sf.PC == f.PC && sf.Function == f.Function {
+			entry.Payload = withStack(entry.Payload, formatStack(pcs[:n], skipped, cfg))
+			return
+		}
+		if !more {
+			return
+		}
+	}
+}
+
+func sourceLocation(f runtime.Frame, cfg SourceLocationConfig) *logpb.LogEntrySourceLocation {
+	file := f.File
+	if cfg.TrimPaths {
+		file = TrimSourceLocation(f.File, f.Function)
+	}
+	return &logpb.LogEntrySourceLocation{File: file, Line: int64(f.Line), Function: f.Function}
+}
+
+func wantsStack(entry *logging.Entry, cfg SourceLocationConfig) bool {
+	return cfg.StackSeverity != logging.Default && entry.Severity >= cfg.StackSeverity
+}
+
+// withStack returns a copy of payload with stack in the StackField field. String
+// payloads are stored in the "message" field. Other payloads are converted to a
+// map if they marshal to a JSON object, or stored in the "value" field otherwise
+// (see NormalizePayload()).
+func withStack(payload interface{}, stack string) interface{} {
+	var m map[string]interface{}
+	switch p := payload.(type) {
+	case nil:
+		m = map[string]interface{}{}
+	case string:
+		m = map[string]interface{}{MessageKey: p}
+	case map[string]interface{}:
+		m = make(map[string]interface{}, len(p)+1)
+		for k, v := range p {
+			m[k] = v
+		}
+	default:
+		m = jsonObject(payload)
+		if m == nil {
+			m = map[string]interface{}{"value": payload}
+		}
+	}
+	m[StackField] = stack
+	return m
+}
+
+// jsonObject returns the fields of payload if it marshals to a JSON object. Numbers
+// are kept as json.Number so that they are not rounded. It returns nil otherwise.
+func jsonObject(payload interface{}) map[string]interface{} {
+	b, err := json.Marshal(payload)
+	if err != nil {
+		return nil
+	}
+	d := json.NewDecoder(bytes.NewReader(b))
+	d.UseNumber()
+	var m map[string]interface{}
+	if d.Decode(&m) != nil {
+		return nil
+	}
+	return m
+}
+
+// siteKey identifies the call site of an entry. It is the source location of the
+// entry if it is set. Otherwise, such as when capture is disabled (see
+// SourceLocationConfig.Disabled), it is the program counter of the call site.
+type siteKey struct {
+	file string
+	line int64
+	pc   uintptr
+}
+
+func newSiteKey(entry logging.Entry) siteKey {
+	if entry.SourceLocation != nil {
+		return siteKey{file: entry.SourceLocation.File, line: entry.SourceLocation.Line}
+	}
+	return siteKey{pc: callSite()}
+}
+
+// String returns the file and line of the call site.
+func (k siteKey) String() string {
+	if k.pc == 0 {
+		return k.file + ":" + strconv.FormatInt(k.line, 10)
+	}
+	fn := runtime.FuncForPC(k.pc)
+	if fn == nil {
+		return "unknown"
+	}
+	file, line := fn.FileLine(k.pc)
+	return file + ":" + strconv.Itoa(line)
+}
+
+// callSite returns the program counter of the first caller outside of the standard
+// log package, the packages of this module and registered helpers. Unlike capturing
+// a source location, it does not need to resolve file names and line numbers.
+func callSite() uintptr {
+	var pcs [32]uintptr
+	n := runtime.Callers(2, pcs[:])
+	fs := runtime.CallersFrames(pcs[:n])
+	for {
+		f, more := fs.Next()
+		pkg := funcPackage(f.Function)
+		if pkg != "log" && pkg != pkgPath && pkg != modulePath && !isHelper(f.Function) {
+			return f.PC
+		}
+		if !more {
+			return 0
+		}
+	}
+}
+
+// formatStack formats pcs in the style of runtime/debug.Stack(), omitting the first skipped frames.
+func formatStack(pcs []uintptr, skipped int, cfg SourceLocationConfig) string {
+	var builder strings.Builder
+	fs := runtime.CallersFrames(pcs)
+	for i := 0; ; i++ {
+		f, more := fs.Next()
+		if i >= skipped {
+			file := f.File
+			if cfg.TrimPaths {
+				file = TrimSourceLocation(f.File, f.Function)
+			}
+			builder.WriteString(f.Function)
+			builder.WriteString("()\n\t")
+			builder.WriteString(file)
+			builder.WriteByte(':')
+			builder.WriteString(strconv.Itoa(f.Line))
+			builder.WriteByte('\n')
+		}
+		if !more {
+			return builder.String()
+		}
+	}
+}
+
+var (
+	pkgMainModuleOnce sync.Once // protects below
+	pkgMainModule     string
+)
+
+// TrimSourceLocation trims the absolute build path of a source file so that it is
+// relative to its module root or GOPATH. function is the fully-qualified name of a
+// function in the file, which is used to find the module root of the main module.
+//
+//	/home/builder/go/pkg/mod/github.com/a/b@v1.0.0/c/d.go -> github.com/a/b@v1.0.0/c/d.go
+//	/home/builder/go/src/github.com/a/b/c/d.go            -> github.com/a/b/c/d.go
+//	/workspace/c/d.go (main module github.com/a/b)          -> c/d.go
+//
+// Other paths are trimmed with TrimSourcePath().
+func TrimSourceLocation(file, function string) string {
+	file = filepath.ToSlash(file)
+
+	if i := strings.LastIndex(file, "/pkg/mod/"); i >= 0 {
+		return file[i+len("/pkg/mod/"):]
+	}
+
+	for _, gopath := range filepath.SplitList(build.Default.GOPATH) {
+		src := filepath.ToSlash(gopath) + "/src/"
+		if strings.HasPrefix(file, src) {
+			return file[len(src):]
+		}
+	}
+
+	pkgMainModuleOnce.Do(func() {
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			pkgMainModule = bi.Main.Path
+		}
+	})
+
+	pkg := funcPackage(function)
+	if pkgMainModule != "" && (pkg == pkgMainModule || strings.HasPrefix(pkg, pkgMainModule+"/")) {
+		rel := strings.TrimPrefix(pkg, pkgMainModule)
+		if dir := path.Dir(file); strings.HasSuffix(dir, rel) {
+			return strings.TrimPrefix(rel+"/"+path.Base(file), "/")
+		}
+	}
+
+	return TrimSourcePath(file)
+}
@@ -0,0 +1,91 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"cloud.google.com/go/logging"
+	"encoding/json"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestSetupSourceLocation_config(t *testing.T) {
+	defer SetSourceLocationConfig(SourceLocationConfig{})
+
+	SetSourceLocationConfig(SourceLocationConfig{Disabled: true})
+	entry := logging.Entry{Severity: logging.Error}
+	SetupSourceLocation(&entry, 0)
+	if entry.SourceLocation != nil {
+		t.Errorf("expected no source location when disabled, got %v", entry.SourceLocation)
+	}
+
+	SetSourceLocationConfig(SourceLocationConfig{StackSeverity: logging.Error})
+	entry = logging.Entry{Severity: logging.Error, Payload: map[string]interface{}{"a": "b"}}
+	SetupSourceLocation(&entry, 0)
+	payload := entry.Payload.(map[string]interface{})
+	stack, _ := payload[StackField].(string)
+	if !strings.HasPrefix(stack, pkgPath+".TestSetupSourceLocation_config()\n") {
+		t.Errorf("expected stack to start at the caller, got %q", stack)
+	}
+	if payload["a"] != "b" {
+		t.Errorf("expected existing fields to be kept, got %v", payload)
+	}
+}
+
+func TestTrimSourceLocation(t *testing.T) {
+	for _, tc := range []struct{ file, function, want string }{
+		{"/home/builder/go/pkg/mod/github.com/a/b@v1.0.0/c/d.go", "github.com/a/b/c.F", "github.com/a/b@v1.0.0/c/d.go"},
+		{"/usr/local/go/src/runtime/proc.go", "runtime.main", "/usr/local/go/src/runtime/proc.go"},
+	} {
+		if got := TrimSourceLocation(tc.file, tc.function); got != tc.want {
+			t.Errorf("TrimSourceLocation(%q, %q) = %q, want %q", tc.file, tc.function, got, tc.want)
+		}
+	}
+}
+
+func TestTrimSourcePath(t *testing.T) {
+	_, file, _, _ := runtime.Caller(0)
+	// The working directory is the package directory, so the path must be
+	// trimmed relative to the module root rather than the working directory.
+	if got, want := TrimSourcePath(file), "internal/log/source_test.go"; got != want {
+		t.Errorf("TrimSourcePath(%q) = %q, want %q", file, got, want)
+	}
+}
+
+func TestWithStack(t *testing.T) {
+	type event struct {
+		ID   int64  `json:"id"`
+		Name string `json:"name"`
+	}
+	for _, tc := range []struct {
+		payload interface{}
+		want    map[string]interface{}
+	}{
+		{nil, map[string]interface{}{StackField: "stack"}},
+		{"failed", map[string]interface{}{"message": "failed", StackField: "stack"}},
+		{map[string]interface{}{"a": 1}, map[string]interface{}{"a": 1, StackField: "stack"}},
+		{event{1 << 60, "x"}, map[string]interface{}{"id": json.Number("1152921504606846976"), "name": "x", StackField: "stack"}},
+		{[]int{1}, map[string]interface{}{"value": []int{1}, StackField: "stack"}},
+	} {
+		if got := withStack(tc.payload, "stack"); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("withStack(%#v) = %#v, want %#v", tc.payload, got, tc.want)
+		}
+	}
+}
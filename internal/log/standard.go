@@ -55,9 +55,9 @@ type standardLogger struct {
 func (s *standardLogger) LogSync(_ context.Context, entry logging.Entry) error {
 	if l, ok := s.bySeverity[entry.Severity]; ok {
 		SetupSourceLocation(&entry, 1)
-		file := path.Base(entry.SourceLocation.File)
+		file := path.Base(entry.SourceLocation.GetFile())
 
-		err := l.Output(5, fmt.Sprintf("%s %7s %v:%v %v", s.logId, entry.Severity, file, entry.SourceLocation.Line, entry.Payload))
+		err := l.Output(5, fmt.Sprintf("%s %7s %v:%v %v", s.logId, entry.Severity, file, entry.SourceLocation.GetLine(), entry.Payload))
 		if err != nil {
 			return err
 		}
@@ -106,9 +106,9 @@ func (s *standardLogger) StandardLogger(severity logging.Severity) *log.Logger {
 func (s *standardLogger) Log(entry logging.Entry) {
 	if l, ok := s.bySeverity[entry.Severity]; ok {
 		SetupSourceLocation(&entry, 1)
-		file := path.Base(entry.SourceLocation.File)
+		file := path.Base(entry.SourceLocation.GetFile())
 
-		_ = l.Output(5, fmt.Sprintf("%s %7s %v:%v %v", s.logId, entry.Severity, file, entry.SourceLocation.Line, entry.Payload))
+		_ = l.Output(5, fmt.Sprintf("%s %7s %v:%v %v", s.logId, entry.Severity, file, entry.SourceLocation.GetLine(), entry.Payload))
 		return
 	}
 
@@ -0,0 +1,67 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"log"
+)
+
+// NewTraceLogger returns a Logger that stamps each entry with the provided
+// trace, span ID and sampling decision before passing it on to l. Entries
+// that already have a trace set are left unchanged.
+func NewTraceLogger(l Logger, trace, spanID string, sampled bool) Logger {
+	return traceLogger{l, trace, spanID, sampled}
+}
+
+type traceLogger struct {
+	Logger
+	trace   string
+	spanID  string
+	sampled bool
+}
+
+func (t traceLogger) setupTrace(entry *logging.Entry) {
+	if entry.Trace != "" {
+		return
+	}
+	entry.Trace = t.trace
+	entry.SpanID = t.spanID
+	entry.TraceSampled = t.sampled
+}
+
+// StandardLogger implements log.Logger.StandardLogger().
+// Entries written to the returned logger are not stamped with the trace.
+func (t traceLogger) StandardLogger(severity logging.Severity) *log.Logger {
+	return t.Logger.StandardLogger(severity)
+}
+
+// Log implements log.Logger.Log().
+func (t traceLogger) Log(entry logging.Entry) {
+	SetupSourceLocation(&entry, 1)
+	t.setupTrace(&entry)
+	t.Logger.Log(entry)
+}
+
+// LogSync implements log.Logger.LogSync().
+func (t traceLogger) LogSync(ctx context.Context, entry logging.Entry) error {
+	SetupSourceLocation(&entry, 1)
+	t.setupTrace(&entry)
+	return t.Logger.LogSync(ctx, entry)
+}
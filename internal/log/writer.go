@@ -0,0 +1,74 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package log
+
+import (
+	"cloud.google.com/go/logging"
+	"log"
+	"runtime"
+	"strings"
+)
+
+// NewStandardLogger returns a *log.Logger that logs each message written to it
+// as an entry with the provided severity. It is useful for implementing
+// Logger.StandardLogger() on loggers that wrap other loggers.
+func NewStandardLogger(l Logger, severity logging.Severity) *log.Logger {
+	return log.New(entryWriter{l, severity}, "", 0)
+}
+
+type entryWriter struct {
+	logger   Logger
+	severity logging.Severity
+}
+
+// Write implements io.Writer. Each call to Write is logged as a single entry with
+// any trailing newline removed.
+func (e entryWriter) Write(p []byte) (int, error) {
+	entry := logging.Entry{Severity: e.severity, Payload: strings.TrimSuffix(string(p), "\n")}
+	setupWriterSourceLocation(&entry)
+	e.logger.Log(entry)
+	return len(p), nil
+}
+
+// setupWriterSourceLocation sets up entry.SourceLocation using the first caller
+// outside of the standard log package, this package and registered helpers.
+func setupWriterSourceLocation(entry *logging.Entry) {
+	cfg := sourceLocationConfig()
+	if cfg.Disabled {
+		return
+	}
+
+	var pcs [16]uintptr
+	n := runtime.Callers(3, pcs[:])
+	fs := runtime.CallersFrames(pcs[:n])
+	for skipped := 0; ; skipped++ {
+		f, more := fs.Next()
+		if !strings.HasPrefix(f.Function, "log.") && !strings.HasPrefix(f.Function, pkgPath+".") && !isHelper(f.Function) {
+			setSourceLocation(entry, f, skipped, 3, cfg)
+			return
+		}
+		if !more {
+			return
+		}
+	}
+}
+
+const (
+	pkgPath    = "github.com/ajjensen13/gke/internal/log"
+	modulePath = "github.com/ajjensen13/gke"
+)
@@ -86,7 +86,9 @@ func AliveContext() (context.Context, context.CancelFunc) {
 
 // AfterAliveContext returns a context that completes when the alive
 // context has been canceled and all functions that were started by
-// calling Go() have returned (or the timeout expires).
+// calling Go() have returned (or the timeout expires). The loggers are
+// flushed (see FlushLoggers()) before the context completes, and before
+// panicking if the program fails to shutdown within the timeout.
 //
 //		// Note: currently this will always be true
 // 		errors.Is(AfterAliveContext(timeout).Err(), context.Canceled)
@@ -100,6 +102,7 @@ func AfterAliveContext(timeout time.Duration) context.Context {
 		<-pkgAlive.Done()
 		<-time.After(timeout)
 		if atomic.LoadInt32(&wf) == 0 {
+			FlushLoggers(FlushTimeout)
 			panic("program failed to shutdown gracefully")
 		}
 	}()
@@ -108,6 +111,7 @@ func AfterAliveContext(timeout time.Duration) context.Context {
 		defer cancelFunc()
 		pkgSyncWaitGroup.Wait()
 		atomic.StoreInt32(&wf, 1)
+		FlushLoggers(FlushTimeout)
 	}()
 
 	return result
@@ -84,9 +84,9 @@ func ExampleAliveContext() {
 	// 0 stopped: cleanup complete
 }
 
-// ExampleAliveContext_WithLogger demonstrates how to use gke.Go()
+// ExampleAliveContext_withLogger demonstrates how to use gke.Go()
 // gke.NewLogger, and gke.AfterAliveContext together.
-func ExampleAliveContext_WithLogger() {
+func ExampleAliveContext_withLogger() {
 	lg, cleanup, err := gke.NewLogger(context.Background())
 	if err != nil {
 		panic(err)
@@ -22,6 +22,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"io"
 	stdlog "log"
 	"os"
 	"path"
@@ -52,41 +53,132 @@ func DefaultLogID() (string, error) {
 // NewLogClient returns a log client. The context should remain open for the life of the log client.
 // Note: ctx should usually be context.Background() to ensure that the logging
 // events occur event after AliveContext() is canceled.
-func NewLogClient(ctx context.Context) (client LogClient, cleanup func(), err error) {
+func NewLogClient(ctx context.Context, opts ...LogClientOption) (client LogClient, cleanup func(), err error) {
+	var cfg logClientConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	client, err = newDefaultLogClient(ctx, &cfg)
+	if err != nil {
+		cfg.closeSinks()
+		return LogClient{}, func() {}, err
+	}
+
+	client = cfg.apply(client)
+	return client, func() { _ = client.Close() }, nil
+}
+
+func newDefaultLogClient(ctx context.Context, cfg *logClientConfig) (LogClient, error) {
 	md, err := Metadata()
 	switch {
 	case errors.Is(err, ErrNotOnGCE):
-		client := log.NewStandardClient(os.Stderr)
-		return LogClient{client}, func() { _ = client.Close() }, nil
-	case err == nil:
-		parent := md.ProjectID
-		client, err := log.NewGkeClient(ctx, "projects/"+parent)
-		if err != nil {
-			return LogClient{}, func() {}, err
-		}
-		err = client.Ping(ctx)
-		if err != nil {
-			return LogClient{}, func() {}, err
+		if log.IsTerminal(log.Stderr) {
+			return NewConsoleLogClient(log.Stderr), nil
 		}
-		return LogClient{client}, func() { _ = client.Close() }, nil
+		return NewStandardLogClient(log.Stderr), nil
+	case err == nil:
+		return newGkeLogClient(ctx, md, cfg)
 	default:
-		return LogClient{}, func() {}, fmt.Errorf("failed to create logging client: %w", err)
+		return LogClient{}, fmt.Errorf("failed to create logging client: %w", err)
+	}
+}
+
+// NewGkeLogClient returns a log client that writes to Cloud Logging, even when not
+// running on GCE. Loggers are configured with the monitored resource and pod labels
+// in md instead of the detected metadata. It is useful for testing against a fake
+// logging service (see LogClientAPIOptions()).
+func NewGkeLogClient(ctx context.Context, md *MetadataType, opts ...LogClientOption) (client LogClient, cleanup func(), err error) {
+	var cfg logClientConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	cfg.gkeOpts = append(cfg.gkeOpts, log.WithMetadata(md))
+	client, err = newGkeLogClient(ctx, md, &cfg)
+	if err != nil {
+		cfg.closeSinks()
+		return LogClient{}, func() {}, err
+	}
+
+	client = cfg.apply(client)
+	return client, func() { _ = client.Close() }, nil
+}
+
+func newGkeLogClient(ctx context.Context, md *MetadataType, cfg *logClientConfig) (LogClient, error) {
+	parent := md.ProjectID
+	client, err := log.NewGkeClient(ctx, "projects/"+parent, cfg.gkeClientOptions()...)
+	if err != nil {
+		return LogClient{}, err
+	}
+	err = client.Ping(ctx)
+	if err != nil {
+		_ = client.Close()
+		return LogClient{}, err
 	}
+	return newLogClient(client), nil
+}
+
+// NewConsoleLogClient returns a log client that writes human-friendly entries to w
+// for local development. Severities are colored if w is a terminal. It is used by
+// NewLogClient() when not running on GCE and os.Stderr is a terminal.
+func NewConsoleLogClient(w io.Writer) LogClient {
+	return newLogClient(log.NewConsoleClient(w, log.IsTerminal(w)))
+}
+
+// NewStandardLogClient returns a log client that writes formatted
+// text entries to w. It is used by NewLogClient() when not running on GCE
+// and os.Stderr is not a terminal, such as in CI or when output is piped.
+func NewStandardLogClient(w io.Writer) LogClient {
+	return newLogClient(log.NewStandardClient(w))
 }
 
 // LogClient is used to provision new loggers and close underlying connections during shutdown.
 type LogClient struct {
 	log.Client
+	reg     *loggerRegistry // nil if the client was not created by this package
+	counted bool            // entries are counted by Client rather than by Logger()
+	audit   log.Client      // Client without filtering or sampling; nil if it is Client
 }
 
-// Logger returns a new Logger.
+func newLogClient(c log.Client) LogClient {
+	return LogClient{Client: c, reg: newLoggerRegistry()}
+}
+
+// Logger returns a Logger for logId. Loggers of clients created by this
+// package are flushed by FlushLoggers() until the client is closed, and
+// calls with the same logId share the underlying logger. The entries logged
+// to it are counted (see LogMetricsSnapshot()).
 func (lc LogClient) Logger(logId string) Logger {
-	return Logger{lc.Client.Logger(logId)}
+	newLogger := func() log.Logger {
+		if lc.counted {
+			return lc.Client.Logger(logId)
+		}
+		return log.NewMetricsLogger(lc.Client.Logger(logId), logId, log.DefaultMetrics)
+	}
+	if lc.reg == nil {
+		return Logger{Logger: newLogger()}
+	}
+	return Logger{Logger: lc.reg.logger(logId, newLogger), pending: lc.reg.pending}
+}
+
+// Close writes the pending reports of loggers returned from Logger.WithDedup()
+// and Logger.WithSampling(), then closes the underlying client. The loggers are
+// no longer flushed by FlushLoggers().
+func (lc LogClient) Close() error {
+	if lc.reg != nil {
+		lc.reg.unregister()
+		flushAll(lc.reg.pending.List())
+	}
+	return lc.Client.Close()
 }
 
 // Logger logs entries to a single log.
 type Logger struct {
 	log.Logger
+	errorReporting bool
+	redactor       *Redactor
+	pending        *log.LoggerSet // wrappers with pending reports; may be nil
 }
 
 // StandardLogger returns a *log.Logger for a given severity.
@@ -283,15 +375,19 @@ func (l Logger) Emergencyf(format string, args ...interface{}) string {
 
 func (l Logger) logErr(severity logging.Severity, err error) error {
 	if err != nil {
-		str := fmt.Sprintf("%v", err)
-		l.logPayload(severity, str)
+		var payload interface{} = fmt.Sprintf("%v", err)
+		if l.errorReporting {
+			payload = NewReportedErrorEvent(err, 2)
+		}
+		l.logPayload(severity, payload)
 	}
 	return err
 }
 
 // DefaultErr creates a log entry with a Default severity with an error as its payload.
 // The error is converted into a string via fmt.Sprintf("%v", err) before sending to
-// avoid possible serialization errors. The return value is err.
+// avoid possible serialization errors. If error reporting is enabled (see WithErrorReporting()),
+// the payload is a ReportedErrorEvent instead. The return value is err.
 //
 // Note: Default means the log entry has no assigned severity level.
 func (l Logger) DefaultErr(err error) error {
@@ -300,7 +396,8 @@ func (l Logger) DefaultErr(err error) error {
 
 // DebugErr creates a log entry with a Debug severity with an error as its payload.
 // The error is converted into a string via fmt.Sprintf("%v", err) before sending to
-// avoid possible serialization errors. The return value is err.
+// avoid possible serialization errors. If error reporting is enabled (see WithErrorReporting()),
+// the payload is a ReportedErrorEvent instead. The return value is err.
 //
 // Note: Debug means debug or trace information.
 func (l Logger) DebugErr(err error) error {
@@ -309,7 +406,8 @@ func (l Logger) DebugErr(err error) error {
 
 // InfoErr creates a log entry with a Info severity with an error as its payload.
 // The error is converted into a string via fmt.Sprintf("%v", err) before sending to
-// avoid possible serialization errors. The return value is err.
+// avoid possible serialization errors. If error reporting is enabled (see WithErrorReporting()),
+// the payload is a ReportedErrorEvent instead. The return value is err.
 //
 // Note: Info means routine information, such as ongoing status or performance.
 func (l Logger) InfoErr(err error) error {
@@ -318,7 +416,8 @@ func (l Logger) InfoErr(err error) error {
 
 // NoticeErr creates a log entry with a Notice severity with an error as its payload.
 // The error is converted into a string via fmt.Sprintf("%v", err) before sending to
-// avoid possible serialization errors. The return value is err.
+// avoid possible serialization errors. If error reporting is enabled (see WithErrorReporting()),
+// the payload is a ReportedErrorEvent instead. The return value is err.
 //
 // Note: Notice means normal but significant events, such as start up, shut down, or configuration.
 func (l Logger) NoticeErr(err error) error {
@@ -327,7 +426,8 @@ func (l Logger) NoticeErr(err error) error {
 
 // WarningErr creates a log entry with a Warning severity with an error as its payload.
 // The error is converted into a string via fmt.Sprintf("%v", err) before sending to
-// avoid possible serialization errors. The return value is err.
+// avoid possible serialization errors. If error reporting is enabled (see WithErrorReporting()),
+// the payload is a ReportedErrorEvent instead. The return value is err.
 //
 // Note: Warning means events that might cause problems.
 func (l Logger) WarningErr(err error) error {
@@ -336,7 +436,8 @@ func (l Logger) WarningErr(err error) error {
 
 // ErrorErr creates a log entry with an Error severity with an error as its payload.
 // The error is converted into a string via fmt.Sprintf("%v", err) before sending to
-// avoid possible serialization errors. The return value is err.
+// avoid possible serialization errors. If error reporting is enabled (see WithErrorReporting()),
+// the payload is a ReportedErrorEvent instead. The return value is err.
 //
 // Note: Error means events that are likely to cause problems.
 func (l Logger) ErrorErr(err error) error {
@@ -345,7 +446,8 @@ func (l Logger) ErrorErr(err error) error {
 
 // CriticalErr creates a log entry with a Critical severity with an error as its payload.
 // The error is converted into a string via fmt.Sprintf("%v", err) before sending to
-// avoid possible serialization errors. The return value is err.
+// avoid possible serialization errors. If error reporting is enabled (see WithErrorReporting()),
+// the payload is a ReportedErrorEvent instead. The return value is err.
 //
 // Note: Critical means events that cause more severe problems or brief outages.
 func (l Logger) CriticalErr(err error) error {
@@ -354,7 +456,8 @@ func (l Logger) CriticalErr(err error) error {
 
 // AlertErr creates a log entry with an Alert severity with an error as its payload.
 // The error is converted into a string via fmt.Sprintf("%v", err) before sending to
-// avoid possible serialization errors. The return value is err.
+// avoid possible serialization errors. If error reporting is enabled (see WithErrorReporting()),
+// the payload is a ReportedErrorEvent instead. The return value is err.
 //
 // Note: Alert means a person must take an action immediately.
 func (l Logger) AlertErr(err error) error {
@@ -363,7 +466,8 @@ func (l Logger) AlertErr(err error) error {
 
 // EmergencyErr creates a log entry with an Emergency severity with an error as its payload.
 // The error is converted into a string via fmt.Sprintf("%v", err) before sending to
-// avoid possible serialization errors. The return value is err.
+// avoid possible serialization errors. If error reporting is enabled (see WithErrorReporting()),
+// the payload is a ReportedErrorEvent instead. The return value is err.
 //
 // Note: Emergency means one or more systems are unusable.
 func (l Logger) EmergencyErr(err error) error {
@@ -372,6 +476,7 @@ func (l Logger) EmergencyErr(err error) error {
 
 // SetupSourceLocation sets up the entry.SourceLocation field if it is not already set. If callDepth is 0, then
 // the source location of the caller to SetupSourceLocation will be used. If 1, then the caller of that caller, etc, etc.
+// Helpers (see Helper()) are skipped. Capture is configured with SetSourceLocationConfig().
 func SetupSourceLocation(entry *logging.Entry, callDepth int) {
 	log.SetupSourceLocation(entry, 1+callDepth)
 }
@@ -0,0 +1,186 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"cloud.google.com/go/logging"
+	"errors"
+	"fmt"
+	"google.golang.org/api/option"
+	"time"
+
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// LogClientOption configures the client returned by NewLogClient().
+type LogClientOption func(*logClientConfig)
+
+type logClientConfig struct {
+	minSeverity logging.Severity
+	sinks       []log.Client
+	sampling    *log.SamplingConfig
+	gkeOpts     []log.GkeClientOption
+	onError     func(error)
+}
+
+// ErrFiltered is returned by Logger.LogSync() when the entry is discarded because
+// its severity is less than the minimum set with LogClientMinSeverity().
+var ErrFiltered = log.ErrFiltered
+
+// LogClientMinSeverity causes the default client to discard entries with a
+// severity less than minSeverity. It does not affect clients added with LogClientSink()
+// or audit events (see LogClient.AuditLogger()). Discarded entries are counted (see
+// LogMetricsSnapshot()), and LogSync() returns an error wrapping ErrFiltered for them.
+func LogClientMinSeverity(minSeverity logging.Severity) LogClientOption {
+	return func(c *logClientConfig) {
+		c.minSeverity = minSeverity
+	}
+}
+
+// LogClientSink fans out entries with a severity of at least minSeverity to
+// client in addition to the default client. It may be specified multiple times.
+// A sink that returns an error or panics does not prevent entries from being written
+// to the other sinks. However, sinks are called one after another, so a sink that
+// blocks, such as a stalled network writer, delays the others. Sinks that may block
+// should buffer entries and write them in the background, as the Cloud Logging
+// client does.
+// The returned client takes ownership of client and closes it during cleanup.
+//
+//	// Send errors to stderr in addition to Cloud Logging.
+//	lc, cleanup, err := gke.NewLogClient(ctx, gke.LogClientSink(gke.NewStandardLogClient(os.Stderr), logging.Error))
+func LogClientSink(client LogClient, minSeverity logging.Severity) LogClientOption {
+	client.reg.unregister() // loggers are provisioned through the returned client
+	return func(c *logClientConfig) {
+		c.sinks = append(c.sinks, log.NewThresholdClient(client.Client, minSeverity))
+	}
+}
+
+// LogClientSampling causes loggers provisioned by the client to sample entries
+// per call site. See Logger.WithSampling().
+func LogClientSampling(cfg SamplingConfig) LogClientOption {
+	return func(c *logClientConfig) {
+		c.sampling = &cfg
+	}
+}
+
+// LogClientDelayThreshold sets the maximum amount of time that an entry is
+// buffered before it is sent to Cloud Logging. See logging.DelayThreshold().
+// It is ignored when not running on GCE.
+func LogClientDelayThreshold(d time.Duration) LogClientOption {
+	return logClientLoggerOption(logging.DelayThreshold(d))
+}
+
+// LogClientEntryCountThreshold sets the maximum number of entries that are
+// buffered before they are sent to Cloud Logging. See logging.EntryCountThreshold().
+// It is ignored when not running on GCE.
+func LogClientEntryCountThreshold(n int) LogClientOption {
+	return logClientLoggerOption(logging.EntryCountThreshold(n))
+}
+
+// LogClientBufferedByteLimit sets the maximum number of bytes that each logger
+// buffers. Entries logged beyond the limit are dropped and reported to the error
+// handler (see LogClientOnError()). See logging.BufferedByteLimit(). It is ignored
+// when not running on GCE.
+func LogClientBufferedByteLimit(n int) LogClientOption {
+	return logClientLoggerOption(logging.BufferedByteLimit(n))
+}
+
+// LogClientConcurrentWriteLimit sets the number of goroutines that each logger
+// uses to send entries to Cloud Logging. See logging.ConcurrentWriteLimit().
+// It is ignored when not running on GCE.
+func LogClientConcurrentWriteLimit(n int) LogClientOption {
+	return logClientLoggerOption(logging.ConcurrentWriteLimit(n))
+}
+
+// LogClientAPIOptions adds options that are passed to the Cloud Logging client,
+// such as option.WithEndpoint(). It is ignored when not running on GCE unless the
+// client is created with NewGkeLogClient().
+func LogClientAPIOptions(opts ...option.ClientOption) LogClientOption {
+	return func(c *logClientConfig) {
+		c.gkeOpts = append(c.gkeOpts, log.WithClientOptions(opts...))
+	}
+}
+
+func logClientLoggerOption(opt logging.LoggerOption) LogClientOption {
+	return func(c *logClientConfig) {
+		c.gkeOpts = append(c.gkeOpts, log.WithLoggerOptions(opt))
+	}
+}
+
+// LogClientOnError sets a function that is called when entries fail to be
+// written asynchronously, such as when the buffer overflows (logging.ErrOverflow)
+// or the logging service cannot be reached. Errors are always counted and reported
+// to the original os.Stderr before f is called. f must return quickly and must not log to the
+// client. It is ignored when not running on GCE.
+func LogClientOnError(f func(err error)) LogClientOption {
+	return func(c *logClientConfig) {
+		c.onError = f
+	}
+}
+
+// handleError counts and reports asynchronous write errors before passing them on to
+// the error handler set with LogClientOnError(). Errors are written to the original
+// os.Stderr (see log.Stderr) rather than the standard logger or the current os.Stderr,
+// because they may be redirected to the failing client (see RedirectStandardLog()).
+// The counts are included in LogMetricsSnapshot().
+func (c *logClientConfig) handleError(err error) {
+	errs := log.DefaultMetrics.AddWriteError()
+	dropped := log.DefaultMetrics.Dropped("", log.DropOverflow)
+	if errors.Is(err, logging.ErrOverflow) {
+		dropped = log.DefaultMetrics.AddDropped("", log.DropOverflow)
+	}
+
+	_, _ = fmt.Fprintf(log.Stderr, "gke: logging client: %v (%d errors, %d entries dropped)\n", err, errs, dropped)
+
+	if c.onError != nil {
+		c.onError(err)
+	}
+}
+
+func (c *logClientConfig) gkeClientOptions() []log.GkeClientOption {
+	return append(c.gkeOpts[:len(c.gkeOpts):len(c.gkeOpts)], log.WithOnError(c.handleError))
+}
+
+func (c *logClientConfig) apply(client LogClient) LogClient {
+	// Count entries below the filter and sampler so that dropped entries are
+	// only counted as dropped.
+	result := log.NewMetricsClient(client.Client, log.DefaultMetrics)
+	client.counted = true
+	if c.minSeverity > logging.Default {
+		// Audit events bypass the filter and sampling (see LogClient.AuditLogger()).
+		client.audit = result
+		if len(c.sinks) > 0 {
+			client.audit = append(log.MultiClient{result}, c.sinks...)
+		}
+		result = log.NewFilterClient(result, c.minSeverity)
+	}
+	if len(c.sinks) > 0 {
+		result = append(log.MultiClient{result}, c.sinks...)
+	}
+	if c.sampling != nil {
+		result = log.NewSamplingClient(result, *c.sampling)
+	}
+	client.Client = result
+	return client
+}
+
+func (c *logClientConfig) closeSinks() {
+	for _, s := range c.sinks {
+		_ = s.Close()
+	}
+}
@@ -28,7 +28,8 @@ import (
 // a new client, then creates a new logger with DefaultLogID.
 // Note: ctx should usually be context.Background() to ensure that the logging
 // events occur event after AliveContext() is canceled.
-func NewLogger(ctx context.Context) (lg Logger, cleanup func(), err error) {
+// The options are passed on to NewLogClient().
+func NewLogger(ctx context.Context, opts ...LogClientOption) (lg Logger, cleanup func(), err error) {
 	panic(wire.Build(NewLogClient, provideDefaultLogger, DefaultLogID))
 }
 
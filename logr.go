@@ -0,0 +1,55 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"flag"
+	"github.com/go-logr/logr"
+	"k8s.io/klog/v2"
+	"strconv"
+
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// NewLogrLogger returns a logr.Logger that writes to lg. V-level 0 messages are
+// logged with Info severity and higher V-levels are logged with Debug severity.
+// Messages with a V-level greater than verbosity are discarded. Values and names
+// are written to the JSON payload alongside the message.
+func NewLogrLogger(lg Logger, verbosity int) logr.Logger {
+	return logr.New(log.NewLogrSink(lg.Logger, verbosity))
+}
+
+// RedirectKlog redirects the output of k8s.io/klog/v2, which is used by client-go
+// and controller-runtime, to lg. klog's own verbosity (the -v flag) is set to
+// verbosity. The cleanup function restores klog's default output.
+//
+// Note: klog does not pass the severity of warnings to the logger, so
+// klog.Warning() messages are logged with Info severity.
+func RedirectKlog(lg Logger, verbosity int) (cleanup func()) {
+	fs := flag.NewFlagSet("klog", flag.ContinueOnError)
+	klog.InitFlags(fs)
+	_ = fs.Set("v", strconv.Itoa(verbosity))
+
+	klog.SetLoggerWithOptions(
+		NewLogrLogger(lg, verbosity),
+		klog.ContextualLogger(true),
+		klog.FlushLogger(func() { _ = lg.Flush() }),
+	)
+
+	return klog.ClearLogger
+}
@@ -0,0 +1,47 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"errors"
+	"fmt"
+	"path"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func ExampleNewLogrLogger() {
+	rec := gketest.NewRecorder()
+	lr := gke.NewLogrLogger(rec.Logger("example"), 1)
+
+	lr = lr.WithName("controller").WithValues("namespace", "default")
+	lr.Info("reconciling", "name", "web")
+	lr.V(1).Info("cache hit")
+	lr.V(2).Info("discarded")
+	lr.Error(errors.New("conflict"), "update failed")
+
+	for _, e := range rec.Entries() {
+		fmt.Println(e.Severity, path.Base(e.SourceLocation.File), e.PayloadString())
+	}
+
+	// Output:
+	// Info logr_test.go {"logger":"controller","message":"reconciling","name":"web","namespace":"default"}
+	// Debug logr_test.go {"logger":"controller","message":"cache hit","namespace":"default"}
+	// Error logr_test.go {"error":"conflict","logger":"controller","message":"update failed","namespace":"default"}
+}
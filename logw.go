@@ -0,0 +1,135 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"cloud.google.com/go/logging"
+
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// BadKey is the key used for values in a key-value list that do not
+// have a valid string key (see Infow()).
+const BadKey = log.BadKey
+
+// NewKeyValues returns a structured payload with msg stored in the "message" field
+// and each key-value pair stored in a field of its own. keysAndValues must alternate
+// between string keys and values. Values without a string key, including a trailing
+// value, are stored in the BadKey field. The "message" key is reserved for msg, so
+// values for it are stored in the BadKey field as well. Errors are converted to their
+// message and time.Duration values are converted to strings (e.g. "1.5s") to keep them readable.
+func NewKeyValues(msg string, keysAndValues ...interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, 1+len(keysAndValues)/2)
+	result[log.MessageKey] = msg
+	log.AddKeyValues(result, keysAndValues...)
+	return result
+}
+
+func (l Logger) logw(severity logging.Severity, msg string, keysAndValues ...interface{}) {
+	l.logPayload(severity, NewKeyValues(msg, keysAndValues...))
+}
+
+// Defaultw creates a log entry with a Default severity with a structured payload built
+// from msg and keysAndValues. See NewKeyValues().
+//
+//	lg.Defaultw("request complete", "status", 200, "latency", time.Since(start))
+//
+// Note: Default means the log entry has no assigned severity level.
+func (l Logger) Defaultw(msg string, keysAndValues ...interface{}) {
+	l.logw(logging.Default, msg, keysAndValues...)
+}
+
+// Debugw creates a log entry with a Debug severity with a structured payload built
+// from msg and keysAndValues. See NewKeyValues().
+//
+//	lg.Debugw("request complete", "status", 200, "latency", time.Since(start))
+//
+// Note: Debug means debug or trace information.
+func (l Logger) Debugw(msg string, keysAndValues ...interface{}) {
+	l.logw(logging.Debug, msg, keysAndValues...)
+}
+
+// Infow creates a log entry with a Info severity with a structured payload built
+// from msg and keysAndValues. See NewKeyValues().
+//
+//	lg.Infow("request complete", "status", 200, "latency", time.Since(start))
+//
+// Note: Info means routine information, such as ongoing status or performance.
+func (l Logger) Infow(msg string, keysAndValues ...interface{}) {
+	l.logw(logging.Info, msg, keysAndValues...)
+}
+
+// Noticew creates a log entry with a Notice severity with a structured payload built
+// from msg and keysAndValues. See NewKeyValues().
+//
+//	lg.Noticew("request complete", "status", 200, "latency", time.Since(start))
+//
+// Note: Notice means normal but significant events, such as start up, shut down, or configuration.
+func (l Logger) Noticew(msg string, keysAndValues ...interface{}) {
+	l.logw(logging.Notice, msg, keysAndValues...)
+}
+
+// Warningw creates a log entry with a Warning severity with a structured payload built
+// from msg and keysAndValues. See NewKeyValues().
+//
+//	lg.Warningw("request complete", "status", 200, "latency", time.Since(start))
+//
+// Note: Warning means events that might cause problems.
+func (l Logger) Warningw(msg string, keysAndValues ...interface{}) {
+	l.logw(logging.Warning, msg, keysAndValues...)
+}
+
+// Errorw creates a log entry with an Error severity with a structured payload built
+// from msg and keysAndValues. See NewKeyValues().
+//
+//	lg.Errorw("request complete", "status", 200, "latency", time.Since(start))
+//
+// Note: Error means events that are likely to cause problems.
+func (l Logger) Errorw(msg string, keysAndValues ...interface{}) {
+	l.logw(logging.Error, msg, keysAndValues...)
+}
+
+// Criticalw creates a log entry with a Critical severity with a structured payload built
+// from msg and keysAndValues. See NewKeyValues().
+//
+//	lg.Criticalw("request complete", "status", 200, "latency", time.Since(start))
+//
+// Note: Critical means events that cause more severe problems or brief outages.
+func (l Logger) Criticalw(msg string, keysAndValues ...interface{}) {
+	l.logw(logging.Critical, msg, keysAndValues...)
+}
+
+// Alertw creates a log entry with an Alert severity with a structured payload built
+// from msg and keysAndValues. See NewKeyValues().
+//
+//	lg.Alertw("request complete", "status", 200, "latency", time.Since(start))
+//
+// Note: Alert means a person must take an action immediately.
+func (l Logger) Alertw(msg string, keysAndValues ...interface{}) {
+	l.logw(logging.Alert, msg, keysAndValues...)
+}
+
+// Emergencyw creates a log entry with an Emergency severity with a structured payload built
+// from msg and keysAndValues. See NewKeyValues().
+//
+//	lg.Emergencyw("request complete", "status", 200, "latency", time.Since(start))
+//
+// Note: Emergency means one or more systems are unusable.
+func (l Logger) Emergencyw(msg string, keysAndValues ...interface{}) {
+	l.logw(logging.Emergency, msg, keysAndValues...)
+}
@@ -0,0 +1,55 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func ExampleLogger_Infow() {
+	rec := gketest.NewRecorder()
+	lg := rec.Logger("example")
+
+	lg.Infow("request complete", "status", 200, "latency", 1500*time.Millisecond, "err", errors.New("none"), "dangling")
+
+	fmt.Println(rec.Entries()[0].PayloadString())
+
+	// Output:
+	// {"!BADKEY":"dangling","err":"none","latency":"1.5s","message":"request complete","status":200}
+}
+
+func ExampleNewKeyValues() {
+	kv := gke.NewKeyValues("odd", "a", 1, 2, "b", 3)
+	fmt.Println(kv["a"], kv["b"], kv[gke.BadKey])
+
+	// Output:
+	// 1 3 2
+}
+
+func ExampleNewKeyValues_reservedMessage() {
+	kv := gke.NewKeyValues("request complete", "message", "overwritten?", "status", 200)
+	fmt.Println(kv["message"], kv["status"], kv[gke.BadKey])
+
+	// Output:
+	// request complete 200 overwritten?
+}
@@ -38,15 +38,19 @@ func Metadata() (md *MetadataType, err error) {
 
 // LogMetadata logs the metadata at Info severity.
 // It is provided for consistency in logging across GKE applications.
+// Secrets are removed with the logger's Redactor (see Logger.WithRedaction()),
+// or DefaultRedactor() if it has none.
 func LogMetadata(lg Logger) {
 	md, err := Metadata()
-	lg.Info(NewMsgData("gke.LogMetadata()", md, err))
+	lg.Info(NewMsgData("gke.LogMetadata()", lg.redactorOrDefault().Redact(md), err))
 }
 
 // LogEnv logs the environment at Info severity.
 // It is provided for consistency in logging across GKE applications.
+// Secrets are removed with the logger's Redactor (see Logger.WithRedaction()),
+// or DefaultRedactor() if it has none.
 func LogEnv(lg Logger) {
-	lg.Info(NewMsgData("gke.LogEnv()", os.Environ()))
+	lg.Info(NewMsgData("gke.LogEnv()", lg.redactorOrDefault().RedactEnv(os.Environ())))
 }
 
 // LogGoRuntime logs runtime information at Info severity.
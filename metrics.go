@@ -0,0 +1,67 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"net/http"
+
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// DropReason describes why an entry was not written. See LogMetrics.
+type DropReason = log.DropReason
+
+const (
+	// DropFiltered is used for entries below the severity set by LogClientMinSeverity().
+	DropFiltered = log.DropFiltered
+	// DropSampled is used for entries suppressed by sampling (see LogClientSampling()).
+	DropSampled = log.DropSampled
+	// DropOverflow is used for entries discarded because the Cloud Logging buffer
+	// overflowed (see LogClientBufferedByteLimit()).
+	DropOverflow = log.DropOverflow
+)
+
+// LogMetrics is a snapshot of the metrics collected by the loggers in this process.
+// Entries are counted by log ID and severity when they are passed on to be written by
+// a Logger returned from LogClient.Logger(), after filtering (see LogClientMinSeverity())
+// and sampling (see LogClientSampling()). Entries written to sinks (see LogClientSink())
+// are not counted separately. Dropped entries are counted by log ID and reason instead.
+// The log ID is empty if it is unknown, such as for Logger.WithSampling() and overflows.
+type LogMetrics = log.MetricsSnapshot
+
+// LoggedCount is the number of entries logged to a log with a severity. See LogMetrics.
+type LoggedCount = log.LoggedCount
+
+// DroppedCount is the number of entries for a log that were dropped for a reason. See LogMetrics.
+type DroppedCount = log.DroppedCount
+
+// LogMetricsSnapshot returns a snapshot of the metrics collected by the loggers in this process.
+func LogMetricsSnapshot() LogMetrics {
+	return log.DefaultMetrics.Snapshot()
+}
+
+// LogMetricsHandler returns a handler that serves the metrics collected by the loggers
+// in this process in the Prometheus text exposition format.
+//
+//	http.Handle("/metrics", gke.LogMetricsHandler())
+//
+// The metrics are gke_log_entries_total{log_id, severity}, gke_log_entries_dropped_total{log_id, reason}
+// and gke_log_write_errors_total.
+func LogMetricsHandler() http.Handler {
+	return log.DefaultMetrics
+}
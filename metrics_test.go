@@ -0,0 +1,54 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"testing"
+
+	"github.com/ajjensen13/gke"
+)
+
+func TestLogMetricsSnapshot_filtered(t *testing.T) {
+	lc, cleanup, err := gke.NewLogClient(context.Background(), gke.LogClientMinSeverity(logging.Emergency))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	lg := lc.Logger("metrics-filter-test")
+	lg.Info("filtered")
+	lg.Warning("filtered")
+
+	for _, c := range gke.LogMetricsSnapshot().Logged {
+		if c.LogID == "metrics-filter-test" {
+			t.Errorf("expected filtered entries not to be counted as logged, got %+v", c)
+		}
+	}
+
+	var dropped int64
+	for _, c := range gke.LogMetricsSnapshot().Dropped {
+		if c.LogID == "metrics-filter-test" && c.Reason == gke.DropFiltered {
+			dropped = c.Count
+		}
+	}
+	if dropped != 2 {
+		t.Errorf("expected 2 filtered entries, got %d", dropped)
+	}
+}
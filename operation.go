@@ -0,0 +1,86 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"cloud.google.com/go/logging"
+	"github.com/google/uuid"
+	logpb "google.golang.org/genproto/googleapis/logging/v2"
+	"time"
+
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// Operation is a Logger that groups its entries into a single operation
+// in Cloud Logging. See Logger.StartOperation().
+type Operation struct {
+	Logger
+	// ID uniquely identifies the operation.
+	ID string
+	// Name is the name passed to StartOperation(). It is used as the operation producer.
+	Name string
+	// Start is the time the operation was started.
+	Start time.Time
+}
+
+// StartOperation logs the start of an operation at Info severity and returns a
+// Logger that stamps every entry with the same operation ID (see logging.Entry.Operation).
+// The first entry is marked as the first entry of the operation. Operation.End() must
+// be called to mark the last entry.
+//
+//	op := lg.StartOperation("nightly-export")
+//	defer func() { op.End(err) }()
+func (l Logger) StartOperation(name string) Operation {
+	op := Operation{Logger: l, ID: uuid.New().String(), Name: name, Start: time.Now()}
+	op.Logger.Logger = log.NewOperationLogger(l.Logger, op.ID, name)
+
+	entry := logging.Entry{
+		Severity:  logging.Info,
+		Payload:   NewKeyValues("operation started", "operation", name),
+		Operation: &logpb.LogEntryOperation{Id: op.ID, Producer: name, First: true},
+	}
+	SetupSourceLocation(&entry, 1)
+	op.Logger.Log(entry)
+
+	return op
+}
+
+// End logs the outcome and duration of the operation and marks the entry as the
+// last entry of the operation. If err is nil, the entry has Info severity. Otherwise,
+// it has Error severity. The return value is err.
+func (o Operation) End(err error) error {
+	outcome, severity := "success", logging.Info
+	if err != nil {
+		outcome, severity = "failure", logging.Error
+	}
+
+	kvs := []interface{}{"operation", o.Name, "outcome", outcome, "duration", time.Since(o.Start)}
+	if err != nil {
+		kvs = append(kvs, "error", err)
+	}
+
+	entry := logging.Entry{
+		Severity:  severity,
+		Payload:   NewKeyValues("operation finished", kvs...),
+		Operation: &logpb.LogEntryOperation{Id: o.ID, Producer: o.Name, Last: true},
+	}
+	SetupSourceLocation(&entry, 1)
+	o.Logger.Log(entry)
+
+	return err
+}
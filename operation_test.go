@@ -0,0 +1,43 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func ExampleLogger_StartOperation() {
+	rec := gketest.NewRecorder()
+	lg := rec.Logger("example")
+
+	op := lg.StartOperation("nightly-export")
+	op.Infof("exported %d rows", 42)
+	_ = op.End(errors.New("upload failed"))
+
+	for _, e := range rec.Entries() {
+		fmt.Println(e.Severity, e.Operation.Id == op.ID, e.Operation.Producer, e.Operation.First, e.Operation.Last)
+	}
+
+	// Output:
+	// Info true nightly-export true false
+	// Info true nightly-export false false
+	// Error true nightly-export false true
+}
@@ -0,0 +1,71 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"regexp"
+
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// Redactor removes secrets from log payloads. See Logger.WithRedaction().
+type Redactor = log.Redactor
+
+// Redacted replaces secrets removed by a Redactor.
+const Redacted = log.Redacted
+
+// DefaultKeyPatterns are the key patterns used by DefaultRedactor().
+var DefaultKeyPatterns = []string{
+	"*PASSWORD*",
+	"*PASSWD*",
+	"*SECRET*",
+	"*TOKEN*",
+	"*API_KEY*",
+	"*APIKEY*",
+	"*PRIVATE_KEY*",
+	"*CREDENTIAL*",
+}
+
+// DefaultValuePatterns are the value patterns used by DefaultRedactor(). They
+// detect JSON Web Tokens, PEM encoded blocks (e.g. private keys) and GCP API keys.
+var DefaultValuePatterns = []*regexp.Regexp{
+	regexp.MustCompile(`eyJ[A-Za-z0-9_-]+\.eyJ[A-Za-z0-9_-]+\.[A-Za-z0-9_-]+`),
+	regexp.MustCompile(`-----BEGIN [A-Z0-9 ]+-----[\s\S]*?-----END [A-Z0-9 ]+-----`),
+	regexp.MustCompile(`AIza[0-9A-Za-z_-]{35}`),
+}
+
+// DefaultRedactor returns a Redactor that uses DefaultKeyPatterns and DefaultValuePatterns.
+func DefaultRedactor() *Redactor {
+	return &Redactor{KeyPatterns: DefaultKeyPatterns, ValuePatterns: DefaultValuePatterns}
+}
+
+// WithRedaction returns a copy of l that removes secrets from the payload and
+// labels of every entry with r. Structured payloads are converted to their JSON
+// representation before being redacted. r is also used by LogEnv() and LogMetadata().
+func (l Logger) WithRedaction(r *Redactor) Logger {
+	l.Logger = log.NewRedactingLogger(l.Logger, r)
+	l.redactor = r
+	return l
+}
+
+func (l Logger) redactorOrDefault() *Redactor {
+	if l.redactor != nil {
+		return l.redactor
+	}
+	return DefaultRedactor()
+}
@@ -0,0 +1,152 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"bufio"
+	"cloud.google.com/go/logging"
+	"fmt"
+	"io"
+	stdlog "log"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// RedirectOption configures RedirectStandardLog().
+type RedirectOption func(*redirectConfig)
+
+type redirectConfig struct {
+	stdout, stderr       bool
+	stdoutSev, stderrSev logging.Severity
+}
+
+// CaptureStdout causes RedirectStandardLog() to replace os.Stdout with a pipe.
+// Each line written to it is logged as an entry with the provided severity.
+func CaptureStdout(severity logging.Severity) RedirectOption {
+	return func(c *redirectConfig) {
+		c.stdout, c.stdoutSev = true, severity
+	}
+}
+
+// CaptureStderr causes RedirectStandardLog() to replace os.Stderr with a pipe.
+// Each line written to it is logged as an entry with the provided severity.
+func CaptureStderr(severity logging.Severity) RedirectOption {
+	return func(c *redirectConfig) {
+		c.stderr, c.stderrSev = true, severity
+	}
+}
+
+// RedirectStandardLog sets lg as the output of the standard library's global
+// logger (see log.SetOutput()). Each message is logged as an entry with the provided
+// severity. Writes to os.Stdout and os.Stderr can be captured as well by passing
+// CaptureStdout() and CaptureStderr(). The cleanup function restores the original
+// output and streams after the captured output has been logged.
+//
+// Note: lg must not write to the captured streams, so it should be created before
+// RedirectStandardLog is called. The client returned by NewLogClient() when not
+// running on GCE holds on to the original os.Stderr, so it is safe to use.
+// Writes made directly to the file descriptors (e.g. by the runtime when a
+// panic occurs) are not captured.
+func RedirectStandardLog(lg Logger, severity logging.Severity, opts ...RedirectOption) (cleanup func(), err error) {
+	var cfg redirectConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var wg sync.WaitGroup
+	var closers []func()
+	restore := func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+		wg.Wait()
+	}
+
+	if cfg.stdout {
+		c, err := capture(&os.Stdout, lg, cfg.stdoutSev, &wg)
+		if err != nil {
+			restore()
+			return func() {}, fmt.Errorf("failed to capture stdout: %w", err)
+		}
+		closers = append(closers, c)
+	}
+
+	if cfg.stderr {
+		c, err := capture(&os.Stderr, lg, cfg.stderrSev, &wg)
+		if err != nil {
+			restore()
+			return func() {}, fmt.Errorf("failed to capture stderr: %w", err)
+		}
+		closers = append(closers, c)
+	}
+
+	w, flags, prefix := stdlog.Writer(), stdlog.Flags(), stdlog.Prefix()
+	stdlog.SetOutput(log.NewStandardLogger(lg.Logger, severity).Writer())
+	stdlog.SetFlags(0)
+	stdlog.SetPrefix("")
+	closers = append(closers, func() {
+		stdlog.SetOutput(w)
+		stdlog.SetFlags(flags)
+		stdlog.SetPrefix(prefix)
+	})
+
+	var once sync.Once
+	return func() { once.Do(restore) }, nil
+}
+
+// capture replaces *f with the write end of a pipe and logs each line read from
+// the other end. The returned function restores *f and closes the pipe.
+func capture(f **os.File, lg Logger, severity logging.Severity, wg *sync.WaitGroup) (func(), error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+
+	orig := *f
+	*f = w
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		defer r.Close()
+		readLines(r, func(line string) {
+			lg.Log(logging.Entry{Severity: severity, Payload: line})
+		})
+	}()
+
+	return func() {
+		*f = orig
+		_ = w.Close()
+	}, nil
+}
+
+func readLines(r io.Reader, f func(line string)) {
+	br := bufio.NewReader(r)
+	for {
+		line, err := br.ReadString('\n')
+		if line = strings.TrimRight(line, "\r\n"); line != "" {
+			f(line)
+		}
+		if err != nil {
+			return
+		}
+	}
+}
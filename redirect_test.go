@@ -0,0 +1,49 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"cloud.google.com/go/logging"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func ExampleRedirectStandardLog() {
+	rec := gketest.NewRecorder()
+
+	cleanup, err := gke.RedirectStandardLog(rec.Logger("example"), logging.Info, gke.CaptureStderr(logging.Warning))
+	if err != nil {
+		panic(err)
+	}
+
+	log.Printf("from the log package")
+	_, _ = fmt.Fprintln(os.Stderr, "from stderr")
+	cleanup()
+
+	for _, e := range rec.Entries() {
+		fmt.Println(e.Severity, e.PayloadString())
+	}
+
+	// Output:
+	// Info from the log package
+	// Warning from stderr
+}
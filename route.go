@@ -0,0 +1,82 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"cloud.google.com/go/logging"
+	"reflect"
+
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// LogRoute dispatches matching entries to the log with ID LogID. See
+// LogClient.RoutingLogger(). An entry matches a route if it matches
+// every one of the route's non-zero conditions.
+type LogRoute struct {
+	// MinSeverity is the minimum severity of matching entries.
+	MinSeverity logging.Severity
+	// MaxSeverity is the maximum severity of matching entries. If it is
+	// logging.Default, then there is no maximum.
+	MaxSeverity logging.Severity
+	// Labels must each be present on matching entries with the same value.
+	// An empty value matches any value for the label.
+	Labels map[string]string
+	// PayloadType is the type of the payload of matching entries. If it is
+	// an interface type, then payloads that implement it match.
+	PayloadType reflect.Type
+	// LogID is the ID of the log that receives matching entries.
+	LogID string
+}
+
+// RoutingLogger returns a logger that dispatches each entry to the log of the first
+// route that matches it. Unmatched entries are logged to defaultLogID. A single logger
+// is provisioned for each distinct log ID.
+//
+//	lg := lc.RoutingLogger("app",
+//		gke.LogRoute{Labels: map[string]string{"audit": ""}, LogID: "audit"},
+//		gke.LogRoute{MinSeverity: logging.Error, LogID: "errors"},
+//	)
+func (lc LogClient) RoutingLogger(defaultLogID string, routes ...LogRoute) Logger {
+	loggers := make(map[string]log.Logger, 1+len(routes))
+	logger := func(logID string) log.Logger {
+		l, ok := loggers[logID]
+		if !ok {
+			l = lc.Logger(logID).Logger
+			loggers[logID] = l
+		}
+		return l
+	}
+
+	rs := make([]log.Route, 0, len(routes))
+	for _, r := range routes {
+		rs = append(rs, log.Route{
+			MinSeverity: r.MinSeverity,
+			MaxSeverity: r.MaxSeverity,
+			Labels:      r.Labels,
+			PayloadType: r.PayloadType,
+			Logger:      logger(r.LogID),
+			LogID:       r.LogID,
+		})
+	}
+
+	result := Logger{Logger: log.NewRoutingLogger(defaultLogID, logger(defaultLogID), rs...)}
+	if lc.reg != nil {
+		result.pending = lc.reg.pending
+	}
+	return result
+}
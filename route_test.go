@@ -0,0 +1,86 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"cloud.google.com/go/logging"
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+type userEvent struct {
+	User string `json:"user"`
+}
+
+func ExampleLogClient_RoutingLogger() {
+	rec := gketest.NewRecorder()
+	lg := rec.LogClient().RoutingLogger("app",
+		gke.LogRoute{Labels: map[string]string{"audit": ""}, LogID: "audit"},
+		gke.LogRoute{PayloadType: reflect.TypeOf(userEvent{}), LogID: "users"},
+		gke.LogRoute{MinSeverity: logging.Error, LogID: "errors"},
+	)
+
+	lg.Info("starting")
+	lg.Log(logging.Entry{Severity: logging.Notice, Payload: "user deleted", Labels: map[string]string{"audit": "true"}})
+	lg.Info(userEvent{User: "alice"})
+	lg.Error("connection refused")
+
+	for _, e := range rec.Entries() {
+		fmt.Println(e.LogID, e.Severity, e.PayloadString())
+	}
+
+	// Output:
+	// app Info starting
+	// audit Notice user deleted
+	// users Info {"user":"alice"}
+	// errors Error connection refused
+}
+
+func TestLogClient_RoutingLogger_fanOut(t *testing.T) {
+	rec := gketest.NewRecorder()
+	lc, cleanup, err := gke.NewLogClient(context.Background(),
+		gke.LogClientMinSeverity(logging.Emergency),
+		gke.LogClientSink(rec.LogClient(), logging.Default),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+
+	lg := lc.RoutingLogger("app",
+		gke.LogRoute{Labels: map[string]string{"audit": ""}, LogID: "audit"},
+		gke.LogRoute{MinSeverity: logging.Error, LogID: "audit"},
+	)
+	lg.Error("connection refused")
+	lg.Info("starting")
+
+	if err := lg.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Find(gketest.LogID("audit"), gketest.Contains("connection refused"))) != 1 {
+		t.Errorf("expected the error to be routed to the sink, got %v", rec.Entries())
+	}
+	if len(rec.Find(gketest.LogID("app"), gketest.Contains("starting"))) != 1 {
+		t.Errorf("expected the default route to reach the sink, got %v", rec.Entries())
+	}
+}
@@ -0,0 +1,42 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// SamplingConfig configures per call site sampling. See Logger.WithSampling().
+// Invalid values are replaced by defaults, so the zero value logs the first 100
+// entries from each call site per second.
+//
+//	// Log the first 10 entries from each call site per second, then every 100th.
+//	cfg := gke.SamplingConfig{Tick: time.Second, First: 10, Thereafter: 100}
+type SamplingConfig = log.SamplingConfig
+
+// WithSampling returns a copy of l that limits the number of entries logged from
+// each call site. Call sites are identified by the entry's source location, which
+// is set up by the severity methods (e.g. Error()), or by the program counter of the
+// caller if source locations are not captured (see SourceLocationConfig.Disabled). When a sampling window ends, the
+// number of suppressed entries is logged with Warning severity, even if the call site
+// stops logging. Pending reports are written by Flush(), FlushLoggers() and
+// LogClient.Close(). Entries logged with LogSync() are never suppressed.
+func (l Logger) WithSampling(cfg SamplingConfig) Logger {
+	l.Logger = log.NewSamplingLogger(l.Logger, cfg, l.pending)
+	return l
+}
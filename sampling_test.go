@@ -0,0 +1,77 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"cloud.google.com/go/logging"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func ExampleLogger_WithSampling() {
+	rec := gketest.NewRecorder()
+	lg := rec.Logger("example").WithSampling(gke.SamplingConfig{Tick: time.Hour, First: 2, Thereafter: 5})
+
+	for i := 1; i <= 12; i++ {
+		lg.Errorf("attempt %d failed", i)
+	}
+	_ = lg.Flush()
+
+	for _, e := range rec.Find(gketest.Severity(logging.Error)) {
+		fmt.Println(e.PayloadString())
+	}
+	fmt.Println(rec.Find(gketest.Severity(logging.Warning))[0].Payload.(map[string]interface{})["suppressed"])
+
+	// Output:
+	// attempt 1 failed
+	// attempt 2 failed
+	// attempt 7 failed
+	// attempt 12 failed
+	// 8
+}
+
+func TestLogger_WithSampling_sourceLocationDisabled(t *testing.T) {
+	gke.SetSourceLocationConfig(gke.SourceLocationConfig{Disabled: true})
+	defer gke.SetSourceLocationConfig(gke.SourceLocationConfig{})
+
+	rec := gketest.NewRecorder()
+	lg := rec.Logger("test").WithSampling(gke.SamplingConfig{Tick: time.Hour, First: 1})
+
+	for i := 0; i < 3; i++ {
+		lg.Info("hot loop")
+	}
+	lg.Info("elsewhere")
+	_ = lg.Flush()
+
+	if n := len(rec.Find(gketest.Contains("elsewhere"))); n != 1 {
+		t.Errorf("expected call sites to be sampled separately, got %d entries from the second call site", n)
+	}
+	reports := rec.Find(gketest.Severity(logging.Warning))
+	if len(reports) != 1 {
+		t.Fatalf("expected 1 report, got %d", len(reports))
+	}
+	msg, _ := reports[0].Payload.(map[string]interface{})["message"].(string)
+	if !strings.HasPrefix(msg, "suppressed 2 log entries from ") || !strings.Contains(msg, "sampling_test.go:") {
+		t.Errorf("expected the report to name the call site, got %q", msg)
+	}
+}
@@ -28,7 +28,7 @@ import (
 
 func provideServer(lg Logger, handler http.Handler) *http.Server {
 	result := http.Server{
-		Handler:           handler,
+		Handler:           TraceHandler(handler),
 		ReadTimeout:       time.Second * 30,
 		ReadHeaderTimeout: time.Second * 5,
 		WriteTimeout:      time.Second * 30,
@@ -29,6 +29,7 @@ import (
 // is initialized with sensible defaults for timeout values. It sets the base context
 // to AliveContext(). It starts a go routine to call Shutdown() when the AliveContext()
 // is canceled. It sets up a ConnContext function to initialize the RequestContextKey data.
+// The handler is wrapped with TraceHandler() so that each request carries a TraceContext.
 func NewServer(ctx context.Context, handler http.Handler, lg Logger) (*http.Server, error) {
 	panic(wire.Build(provideServer))
 }
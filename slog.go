@@ -0,0 +1,41 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"log/slog"
+
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// SlogHandlerOptions configures a handler returned by NewSlogHandler().
+type SlogHandlerOptions = log.SlogHandlerOptions
+
+// NewSlogHandler returns a slog.Handler that writes records to lg. Record levels
+// are mapped to severities (e.g. slog.LevelWarn is logging.Warning) and attributes
+// are written to the JSON payload, nested by group.
+func NewSlogHandler(lg Logger, opts *SlogHandlerOptions) slog.Handler {
+	return log.NewSlogHandler(lg.Logger, opts)
+}
+
+// NewSlogLogger returns a *slog.Logger that writes records to lg.
+//
+//	slog.SetDefault(gke.NewSlogLogger(lg))
+func NewSlogLogger(lg Logger) *slog.Logger {
+	return slog.New(NewSlogHandler(lg, nil))
+}
@@ -0,0 +1,95 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"cloud.google.com/go/logging"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func ExampleNewSlogLogger() {
+	rec := gketest.NewRecorder()
+	sl := gke.NewSlogLogger(rec.Logger("example"))
+
+	sl.With("user", "alice").WithGroup("req").Warn("slow request", "path", "/", slog.Group("timing", "ms", 1200))
+
+	e := rec.Entries()[0]
+	fmt.Println(e.Severity, e.SourceLocation != nil, e.PayloadString())
+
+	// Output:
+	// Warning true {"message":"slow request","req":{"path":"/","timing":{"ms":1200}},"user":"alice"}
+}
+
+func ExampleNewSlogHandler() {
+	rec := gketest.NewRecorder()
+	h := gke.NewSlogHandler(rec.Logger("example"), &gke.SlogHandlerOptions{Level: slog.LevelDebug, AttrsAsLabels: true})
+
+	slog.New(h).WithGroup("k8s").With("pod", "web-0").Debug("probe")
+
+	e := rec.Entries()[0]
+	fmt.Println(e.Severity, e.Labels, e.PayloadString())
+
+	// Output:
+	// Debug map[k8s.pod:web-0] {"message":"probe"}
+}
+
+func TestNewSlogLogger_reservedMessage(t *testing.T) {
+	rec := gketest.NewRecorder()
+	sl := gke.NewSlogLogger(rec.Logger("test"))
+	sl.With("message", "first").Info("request complete", "message", "second", slog.Group("req", "message", "grouped"))
+
+	want := `{"!BADKEY":["first","second"],"message":"request complete","req":{"message":"grouped"}}`
+	if got := rec.Entries()[0].PayloadString(); got != want {
+		t.Errorf("expected %s, got %s", want, got)
+	}
+}
+
+func TestNewSlogLogger_sourceLocationConfig(t *testing.T) {
+	defer gke.SetSourceLocationConfig(gke.SourceLocationConfig{})
+
+	gke.SetSourceLocationConfig(gke.SourceLocationConfig{Disabled: true})
+	rec := gketest.NewRecorder()
+	gke.NewSlogLogger(rec.Logger("test")).Info("disabled")
+	if loc := rec.Entries()[0].SourceLocation; loc != nil {
+		t.Errorf("expected no source location when capture is disabled, got %v", loc)
+	}
+
+	gke.SetSourceLocationConfig(gke.SourceLocationConfig{TrimPaths: true, StackSeverity: logging.Error})
+	rec = gketest.NewRecorder()
+	sl := gke.NewSlogLogger(rec.Logger("test"))
+	sl.Info("trimmed")
+	sl.Error("failed")
+
+	es := rec.Entries()
+	if loc := es[0].SourceLocation; loc == nil || loc.File != "slog_test.go" {
+		t.Errorf("expected a trimmed source location, got %v", loc)
+	}
+	p, ok := es[1].Payload.(map[string]interface{})
+	if !ok {
+		t.Fatalf("expected a map payload with a stack trace, got %#v", es[1].Payload)
+	}
+	if stack, _ := p[gke.StackField].(string); !strings.HasPrefix(stack, "github.com/ajjensen13/gke_test.TestNewSlogLogger_sourceLocationConfig()\n\tslog_test.go:") {
+		t.Errorf("expected the stack trace to start at the call site, got %q", stack)
+	}
+}
@@ -0,0 +1,62 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// SourceLocationConfig configures how source locations are captured for log entries.
+// See SetSourceLocationConfig().
+type SourceLocationConfig = log.SourceLocationConfig
+
+// StackField is the payload field used for stack traces captured for entries with at
+// least SourceLocationConfig.StackSeverity. String payloads are moved to the "message"
+// field so that the stack trace can be added.
+const StackField = log.StackField
+
+// SetSourceLocationConfig sets how source locations are captured for all loggers in
+// the process. By default, source locations are captured with absolute build paths
+// and without stack traces.
+//
+//	gke.SetSourceLocationConfig(gke.SourceLocationConfig{TrimPaths: true, StackSeverity: logging.Error})
+func SetSourceLocationConfig(cfg SourceLocationConfig) {
+	log.SetSourceLocationConfig(cfg)
+}
+
+// Helper marks the calling function as a logging helper, similar to testing.T.Helper().
+// When capturing source locations, helpers are skipped so that entries logged by
+// wrapper functions are attributed to the callers of the wrappers.
+//
+//	func logRetry(lg gke.Logger, err error) {
+//		gke.Helper()
+//		lg.Warningf("retrying: %v", err)
+//	}
+func Helper() {
+	log.RegisterHelper(1)
+}
+
+// RegisterHelperPackage marks every function in the package with import path
+// pkgPath as a logging helper. See Helper(). It is useful for wrapper libraries.
+//
+//	func init() {
+//		gke.RegisterHelperPackage("github.com/example/mylog")
+//	}
+func RegisterHelperPackage(pkgPath string) {
+	log.RegisterHelperPackage(pkgPath)
+}
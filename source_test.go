@@ -0,0 +1,55 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"cloud.google.com/go/logging"
+	"fmt"
+	"strings"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func logRetry(lg gke.Logger, err string) {
+	gke.Helper()
+	lg.Warningf("retrying: %v", err)
+}
+
+func ExampleHelper() {
+	gke.SetSourceLocationConfig(gke.SourceLocationConfig{TrimPaths: true, StackSeverity: logging.Error})
+	defer gke.SetSourceLocationConfig(gke.SourceLocationConfig{})
+
+	rec := gketest.NewRecorder()
+	lg := rec.Logger("example")
+
+	logRetry(lg, "connection refused")
+	lg.Error("giving up")
+
+	for _, e := range rec.Entries() {
+		msg, hasStack := e.Payload, false
+		if p, ok := e.Payload.(map[string]interface{}); ok {
+			msg, hasStack = p["message"], p[gke.StackField] != nil
+		}
+		fmt.Println(msg, e.SourceLocation.File, strings.TrimPrefix(e.SourceLocation.Function, "github.com/ajjensen13/gke_test."), hasStack)
+	}
+
+	// Output:
+	// retrying: connection refused source_test.go ExampleHelper false
+	// giving up source_test.go ExampleHelper true
+}
@@ -20,10 +20,18 @@ package gke
 import (
 	"cloud.google.com/go/storage"
 	"context"
+	"google.golang.org/api/option"
+	"os"
 )
 
 func provideStorageClient(ctx context.Context) (StorageClient, func(), error) {
-	result, err := storage.NewClient(ctx)
+	var opts []option.ClientOption
+	if host := os.Getenv("STORAGE_EMULATOR_HOST"); host != "" {
+		// The storage client only uses the emulator for reads unless the endpoint is set.
+		opts = append(opts, option.WithEndpoint("http://"+host+"/storage/v1/"))
+	}
+
+	result, err := storage.NewClient(ctx, opts...)
 	if err != nil {
 		return nil, nil, err
 	}
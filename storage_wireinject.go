@@ -25,6 +25,8 @@ import (
 )
 
 // NewStorageClient creates a new Google Cloud Storage client.
+// If the STORAGE_EMULATOR_HOST environment variable is set, then the client
+// connects to the emulator at that host instead.
 func NewStorageClient(ctx context.Context) (StorageClient, func(), error) {
 	panic(wire.Build(provideStorageClient))
 }
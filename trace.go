@@ -0,0 +1,224 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ajjensen13/gke/internal/log"
+)
+
+// TraceContext identifies the trace and span that a request belongs to.
+type TraceContext struct {
+	// TraceID is the 32 character hex encoded trace ID.
+	TraceID string
+	// SpanID is the 16 character hex encoded span ID.
+	SpanID string
+	// Sampled is true if the trace was sampled by the caller.
+	Sampled bool
+}
+
+const (
+	// CloudTraceContextHeader is the header used by Google Cloud load balancers
+	// to propagate trace context. It has the form TRACE_ID/SPAN_ID;o=TRACE_TRUE.
+	CloudTraceContextHeader = "X-Cloud-Trace-Context"
+	// TraceparentHeader is the W3C Trace Context header. It has the form
+	// VERSION-TRACE_ID-PARENT_ID-FLAGS.
+	TraceparentHeader = "traceparent"
+)
+
+// NewTraceContext returns a TraceContext with a randomly generated trace ID and span ID.
+func NewTraceContext() TraceContext {
+	return TraceContext{TraceID: randomHexID(16), SpanID: randomHexID(8)}
+}
+
+// NewSpan returns a TraceContext for a child span of tc. It has the same trace ID
+// and sampling decision as tc and a randomly generated span ID. It is used to
+// propagate the trace context to outgoing requests.
+func (tc TraceContext) NewSpan() TraceContext {
+	tc.SpanID = randomHexID(8)
+	return tc
+}
+
+func randomHexID(n int) string {
+	b := make([]byte, n)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(fmt.Errorf("failed to generate trace context: %w", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// ParseTraceContext parses the trace context from the request headers. The W3C
+// traceparent header is preferred over the X-Cloud-Trace-Context header. If neither
+// header is present or valid, then ok will be false.
+func ParseTraceContext(h http.Header) (tc TraceContext, ok bool) {
+	if tc, ok = parseTraceparent(h.Get(TraceparentHeader)); ok {
+		return
+	}
+	return parseCloudTraceContext(h.Get(CloudTraceContextHeader))
+}
+
+func parseTraceparent(s string) (TraceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(s), "-")
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" {
+		return TraceContext{}, false
+	}
+	if parts[0] == "00" && len(parts) != 4 {
+		return TraceContext{}, false
+	}
+
+	traceID, spanID, flags := strings.ToLower(parts[1]), strings.ToLower(parts[2]), parts[3]
+	if !isHexID(traceID, 32) || !isHexID(spanID, 16) || len(flags) != 2 {
+		return TraceContext{}, false
+	}
+
+	f, err := strconv.ParseUint(flags, 16, 8)
+	if err != nil {
+		return TraceContext{}, false
+	}
+
+	return TraceContext{TraceID: traceID, SpanID: spanID, Sampled: f&1 == 1}, true
+}
+
+func parseCloudTraceContext(s string) (TraceContext, bool) {
+	s = strings.TrimSpace(s)
+	opts := ""
+	if i := strings.IndexByte(s, ';'); i >= 0 {
+		s, opts = s[:i], s[i+1:]
+	}
+
+	traceID, spanID := s, ""
+	if i := strings.IndexByte(s, '/'); i >= 0 {
+		traceID, spanID = s[:i], s[i+1:]
+	}
+
+	traceID = strings.ToLower(traceID)
+	if !isHexID(traceID, 32) {
+		return TraceContext{}, false
+	}
+
+	result := TraceContext{TraceID: traceID, Sampled: opts == "o=1"}
+	if spanID != "" {
+		id, err := strconv.ParseUint(spanID, 10, 64)
+		if err != nil {
+			return TraceContext{}, false
+		}
+		if id != 0 {
+			result.SpanID = fmt.Sprintf("%016x", id)
+		}
+	}
+
+	return result, true
+}
+
+func isHexID(s string, n int) bool {
+	if len(s) != n || strings.Trim(s, "0") == "" {
+		return false
+	}
+	_, err := hex.DecodeString(s)
+	return err == nil
+}
+
+// TraceName returns the fully qualified trace name expected by Cloud Logging
+// (projects/PROJECT_ID/traces/TRACE_ID). The project ID is detected with Metadata().
+// If the project ID cannot be detected, then the bare trace ID is returned.
+func (tc TraceContext) TraceName() string {
+	md, err := Metadata()
+	if err != nil {
+		return tc.TraceID
+	}
+	return tc.ProjectTraceName(md.ProjectID)
+}
+
+// ProjectTraceName returns the fully qualified trace name of tc in projectID
+// (projects/PROJECT_ID/traces/TRACE_ID). If projectID is empty, then the bare
+// trace ID is returned.
+func (tc TraceContext) ProjectTraceName(projectID string) string {
+	if projectID == "" {
+		return tc.TraceID
+	}
+	return "projects/" + projectID + "/traces/" + tc.TraceID
+}
+
+// Traceparent returns tc formatted as a W3C traceparent header value. The
+// header requires a span ID, so one is generated if tc.SpanID is empty, such
+// as when tc was parsed from an X-Cloud-Trace-Context header without one. To
+// propagate the trace context to outgoing requests, use tc.NewSpan().Traceparent().
+func (tc TraceContext) Traceparent() string {
+	spanID := tc.SpanID
+	if spanID == "" {
+		spanID = randomHexID(8)
+	}
+	flags := "00"
+	if tc.Sampled {
+		flags = "01"
+	}
+	return "00-" + tc.TraceID + "-" + spanID + "-" + flags
+}
+
+type traceContextKey struct{}
+
+// WithTraceContext returns a copy of ctx that carries tc.
+func WithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceContextFromContext returns the TraceContext stored in ctx, if any.
+func TraceContextFromContext(ctx context.Context) (tc TraceContext, ok bool) {
+	tc, ok = ctx.Value(traceContextKey{}).(TraceContext)
+	return
+}
+
+// TraceHandler returns a handler that parses the trace context from the
+// request headers and stores it in the request context before calling h.
+// If the request has no trace context, then a new one is generated.
+// Use TraceContextFromContext() or Logger.WithContext() to access it.
+//
+// Servers returned from NewServer() are already wrapped with TraceHandler.
+func TraceHandler(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := TraceContextFromContext(r.Context()); !ok {
+			tc, ok := ParseTraceContext(r.Header)
+			if !ok {
+				tc = NewTraceContext()
+			}
+			r = r.WithContext(WithTraceContext(r.Context(), tc))
+		}
+		h.ServeHTTP(w, r)
+	})
+}
+
+// WithContext returns a Logger that stamps each entry with the trace context
+// stored in ctx, and labels each entry with the request ID stored in ctx (see
+// WithRequestID()). If ctx has neither, then l is returned unchanged.
+func (l Logger) WithContext(ctx context.Context) Logger {
+	if tc, ok := TraceContextFromContext(ctx); ok {
+		l.Logger = log.NewTraceLogger(l.Logger, tc.TraceName(), tc.SpanID, tc.Sampled)
+	}
+	if id, ok := RequestIDFromContext(ctx); ok {
+		l.Logger = log.NewLabelsLogger(l.Logger, map[string]string{RequestIDLabel: id})
+	}
+	return l
+}
@@ -0,0 +1,144 @@
+/*
+Copyright © 2020 A. Jensen <jensen.aaro@gmail.com>
+
+This program is free software: you can redistribute it and/or modify
+it under the terms of the GNU General Public License as published by
+the Free Software Foundation, either version 3 of the License, or
+(at your option) any later version.
+
+This program is distributed in the hope that it will be useful,
+but WITHOUT ANY WARRANTY; without even the implied warranty of
+MERCHANTABILITY or FITNESS FOR A PARTICULAR PURPOSE.  See the
+GNU General Public License for more details.
+
+You should have received a copy of the GNU General Public License
+along with this program. If not, see <http://www.gnu.org/licenses/>.
+*/
+
+package gke_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ajjensen13/gke"
+	"github.com/ajjensen13/gke/gketest"
+)
+
+func ExampleParseTraceContext() {
+	h := http.Header{}
+	h.Set(gke.CloudTraceContextHeader, "105445aa7843bc8bf206b12000100000/1;o=1")
+	tc, ok := gke.ParseTraceContext(h)
+	fmt.Println(tc.TraceID, tc.SpanID, tc.Sampled, ok)
+
+	h.Set(gke.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00")
+	tc, ok = gke.ParseTraceContext(h)
+	fmt.Println(tc.TraceID, tc.SpanID, tc.Sampled, ok)
+
+	// Output:
+	// 105445aa7843bc8bf206b12000100000 0000000000000001 true true
+	// 4bf92f3577b34da6a3ce929d0e0e4736 00f067aa0ba902b7 false true
+}
+
+func TestTraceHandler(t *testing.T) {
+	var got []gke.TraceContext
+	h := gke.TraceHandler(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		tc, ok := gke.TraceContextFromContext(r.Context())
+		if !ok {
+			t.Error("expected trace context in request context")
+		}
+		got = append(got, tc)
+	}))
+
+	r := httptest.NewRequest("GET", "/", nil)
+	r.Header.Set(gke.TraceparentHeader, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+
+	want := gke.TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}
+	if got[0] != want {
+		t.Errorf("expected parsed trace context %+v, got %+v", want, got[0])
+	}
+	if len(got[1].TraceID) != 32 || len(got[1].SpanID) != 16 {
+		t.Errorf("expected generated trace context, got %+v", got[1])
+	}
+	if got[1].TraceID == got[2].TraceID {
+		t.Errorf("expected a new trace for each request, got %q twice", got[1].TraceID)
+	}
+}
+
+func TestLogger_WithContext(t *testing.T) {
+	rec := gketest.NewRecorder()
+	lg := rec.Logger("test")
+
+	tc := gke.TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}
+	lg.WithContext(gke.WithTraceContext(context.Background(), tc)).Info("traced")
+	lg.WithContext(context.Background()).Info("untraced")
+
+	es := rec.Entries()
+	if len(es) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(es))
+	}
+	if es[0].Trace != tc.TraceName() || es[0].SpanID != tc.SpanID || !es[0].TraceSampled {
+		t.Errorf("expected entry to be stamped with %+v, got trace %q span %q sampled %v", tc, es[0].Trace, es[0].SpanID, es[0].TraceSampled)
+	}
+	if es[1].Trace != "" {
+		t.Errorf("expected entry without trace, got %q", es[1].Trace)
+	}
+}
+
+func TestTraceContext_TraceName(t *testing.T) {
+	if _, err := gke.Metadata(); err == nil {
+		t.Skip("the project ID is detected on GCE")
+	}
+
+	tc := gke.TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"}
+	if got := tc.TraceName(); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the bare trace ID when not on GCE, got %q", got)
+	}
+}
+
+func TestTraceContext_ProjectTraceName(t *testing.T) {
+	tc := gke.TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"}
+	if got := tc.ProjectTraceName("my-project"); got != "projects/my-project/traces/4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected fully qualified trace name, got %q", got)
+	}
+	if got := tc.ProjectTraceName(""); got != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Errorf("expected the bare trace ID without a project, got %q", got)
+	}
+}
+
+func TestTraceContext_Traceparent(t *testing.T) {
+	for _, tc := range []gke.TraceContext{
+		{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+		{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"},
+		gke.NewTraceContext(),
+	} {
+		h := http.Header{}
+		h.Set(gke.TraceparentHeader, tc.Traceparent())
+		got, ok := gke.ParseTraceContext(h)
+		if !ok {
+			t.Errorf("expected %q to be parsed", h.Get(gke.TraceparentHeader))
+			continue
+		}
+		if got.TraceID != tc.TraceID || got.Sampled != tc.Sampled || tc.SpanID != "" && got.SpanID != tc.SpanID {
+			t.Errorf("expected %+v, got %+v", tc, got)
+		}
+	}
+}
+
+func TestTraceContext_NewSpan(t *testing.T) {
+	parent := gke.TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}
+	child := parent.NewSpan()
+	if child.TraceID != parent.TraceID || child.Sampled != parent.Sampled {
+		t.Errorf("expected child of %+v, got %+v", parent, child)
+	}
+	if len(child.SpanID) != 16 || child.SpanID == parent.SpanID {
+		t.Errorf("expected a new span ID, got %q", child.SpanID)
+	}
+}
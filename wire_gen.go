@@ -16,8 +16,9 @@ import (
 // a new client, then creates a new logger with DefaultLogID.
 // Note: ctx should usually be context.Background() to ensure that the logging
 // events occur event after AliveContext() is canceled.
-func NewLogger(ctx context.Context) (Logger, func(), error) {
-	logClient, cleanup, err := NewLogClient(ctx)
+// The options are passed on to NewLogClient().
+func NewLogger(ctx context.Context, opts ...LogClientOption) (Logger, func(), error) {
+	logClient, cleanup, err := NewLogClient(ctx, opts...)
 	if err != nil {
 		return Logger{}, nil, err
 	}
@@ -38,6 +39,7 @@ func NewLogger(ctx context.Context) (Logger, func(), error) {
 // is initialized with sensible defaults for timeout values. It sets the base context
 // to AliveContext(). It starts a go routine to call Shutdown() when the AliveContext()
 // is canceled. It sets up a ConnContext function to initialize the RequestContextKey data.
+// The handler is wrapped with TraceHandler() so that each request carries a TraceContext.
 func NewServer(ctx context.Context, handler http.Handler, lg Logger) (*http.Server, error) {
 	server := provideServer(lg, handler)
 	return server, nil
@@ -46,6 +48,8 @@ func NewServer(ctx context.Context, handler http.Handler, lg Logger) (*http.Serv
 // Injectors from storage_wireinject.go:
 
 // NewStorageClient creates a new Google Cloud Storage client.
+// If the STORAGE_EMULATOR_HOST environment variable is set, then the client
+// connects to the emulator at that host instead.
 func NewStorageClient(ctx context.Context) (StorageClient, func(), error) {
 	storageClient, cleanup, err := provideStorageClient(ctx)
 	if err != nil {